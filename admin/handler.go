@@ -0,0 +1,316 @@
+// Package admin provides an HTTP handler for inspecting and changing the configuration
+// of the logger at runtime, for example from a debug server.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/log"
+)
+
+// maxBodyBytes is the maximum size of a request body.
+const maxBodyBytes = 1 << 20
+
+// Config is the configuration of the logger exposed by the handler.
+type Config struct {
+	// Level is the root level of the output sink.
+	Level log.Level `json:"level"`
+	// ScopeLevels overrides Level for specific scopes and their children.
+	ScopeLevels map[string]log.Level `json:"scopeLevels"`
+	// Sampling configures sampling of entries with identical messages.
+	Sampling *Sampling `json:"sampling"`
+	// SamplingRules overrides Sampling for entries from specific scopes and levels.
+	SamplingRules []SamplingRule `json:"samplingRules"`
+	// Sinks are the names of enabled sinks. It is read-only, since sinks cannot be
+	// enabled after initialization.
+	Sinks []string `json:"sinks,omitempty"`
+}
+
+// Sampling configures sampling of entries with identical messages, see
+// log.OutputSampling.
+type Sampling struct {
+	Initial    int `json:"initial"`
+	Thereafter int `json:"thereafter"`
+}
+
+// SamplingRule overrides sampling for entries from specific scopes and levels, see
+// log.OutputSamplingRule.
+type SamplingRule struct {
+	Scope      string    `json:"scope"`
+	Level      log.Level `json:"level,omitempty"`
+	Initial    int       `json:"initial"`
+	Thereafter int       `json:"thereafter"`
+}
+
+// SamplingDrop is the number of entries dropped by sampling, see log.SamplingDrop.
+type SamplingDrop struct {
+	Scope   string    `json:"scope"`
+	Level   log.Level `json:"level"`
+	Message string    `json:"message"`
+	Dropped uint64    `json:"dropped"`
+}
+
+// SentryStats is the cumulative counts of reports handled by the Sentry sink, see
+// log.SentryStats.
+type SentryStats struct {
+	Queued      uint64 `json:"queued"`
+	Dropped     uint64 `json:"dropped"`
+	RateLimited uint64 `json:"rateLimited"`
+	Sent        uint64 `json:"sent"`
+	Discarded   uint64 `json:"discarded"`
+	Failed      uint64 `json:"failed"`
+}
+
+type handler struct {
+	callbacks *log.PostInitCallbacks
+	logger    log.Logger
+}
+
+// NewHandler returns an http.Handler that exposes the configuration of the logger
+// initialized with the given callbacks, which are returned by log.Init. It serves:
+//
+//   - GET /: the current Config as JSON.
+//   - PUT /: replace the configuration of the output sink with the Config in the
+//     request body. Unset values are restored from the environment.
+//   - GET /scopes: the names of all known scopes as a JSON array, see log.KnownScopes.
+//   - GET /drops: the cumulative number of entries dropped by sampling as a JSON array
+//     of SamplingDrop, most dropped first.
+//   - GET /logs: recent entries kept by the memory sink as a JSON array of
+//     log.MemoryEntry, if a memory sink was provided to log.Init. Entries are selected
+//     with the query parameters "level", "scope", "since" and "until" (in RFC 3339
+//     format), "limit", and "field.NAME=VALUE" for field values - see log.MemoryQuery.
+//   - GET /sentry: the cumulative counts of reports handled by the Sentry sink as
+//     SentryStats, if a Sentry sink was provided to log.Init.
+//
+// Paths are relative to where the handler is mounted, so use http.StripPrefix to mount
+// the handler under a prefix.
+func NewHandler(callbacks *log.PostInitCallbacks) http.Handler {
+	return &handler{
+		callbacks: callbacks,
+		logger:    log.Scoped("log.admin"),
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "":
+		switch r.Method {
+		case http.MethodGet:
+			h.getConfig(w)
+		case http.MethodPut:
+			h.putConfig(w, r)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case "/scopes":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, log.KnownScopes())
+	case "/drops":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.getDrops(w)
+	case "/logs":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.getLogs(w, r)
+	case "/sentry":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.getSentryStats(w)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *handler) getConfig(w http.ResponseWriter) {
+	writeJSON(w, h.config())
+}
+
+func (h *handler) getDrops(w http.ResponseWriter) {
+	sampled := h.callbacks.SamplingDrops()
+	drops := make([]SamplingDrop, 0, len(sampled))
+	for _, d := range sampled {
+		drops = append(drops, SamplingDrop(d))
+	}
+	writeJSON(w, drops)
+}
+
+func (h *handler) getSentryStats(w http.ResponseWriter) {
+	stats, ok := h.callbacks.SentryStats()
+	if !ok {
+		http.Error(w, "Sentry sink is not enabled", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, SentryStats(stats))
+}
+
+func (h *handler) getLogs(w http.ResponseWriter, r *http.Request) {
+	logs := h.callbacks.MemoryLogs()
+	if logs == nil {
+		http.Error(w, "memory sink is not enabled", http.StatusNotFound)
+		return
+	}
+
+	q, err := parseMemoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := logs.Query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, entries)
+}
+
+// parseMemoryQuery parses a log.MemoryQuery from query parameters.
+func parseMemoryQuery(values url.Values) (q log.MemoryQuery, err error) {
+	q.Level = log.Level(values.Get("level"))
+	if err := validateLevel(q.Level); err != nil {
+		return q, err
+	}
+	q.Scope = values.Get("scope")
+	if v := values.Get("since"); v != "" {
+		if q.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return q, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if v := values.Get("until"); v != "" {
+		if q.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return q, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return q, fmt.Errorf("invalid limit: %w", err)
+		}
+	}
+	for key := range values {
+		if name := strings.TrimPrefix(key, "field."); name != key {
+			if q.Fields == nil {
+				q.Fields = make(map[string]string)
+			}
+			q.Fields[name] = values.Get(key)
+		}
+	}
+	return q, nil
+}
+
+func (h *handler) config() Config {
+	output := h.callbacks.OutputConfig()
+	c := Config{
+		Level:       output.Level,
+		ScopeLevels: output.ScopeLevels,
+		Sinks:       h.callbacks.SinkNames(),
+	}
+	if output.Sampling != nil {
+		c.Sampling = &Sampling{
+			Initial:    output.Sampling.Initial,
+			Thereafter: output.Sampling.Thereafter,
+		}
+	}
+	if output.SamplingRules != nil {
+		c.SamplingRules = make([]SamplingRule, 0, len(output.SamplingRules))
+		for _, r := range output.SamplingRules {
+			c.SamplingRules = append(c.SamplingRules, SamplingRule(r))
+		}
+	}
+	return c
+}
+
+func (h *handler) putConfig(w http.ResponseWriter, r *http.Request) {
+	var c Config
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&c); err != nil {
+		http.Error(w, fmt.Sprintf("invalid config: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := validateLevel(c.Level); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for scope, level := range c.ScopeLevels {
+		if scope == "" {
+			http.Error(w, "scope must not be empty", http.StatusBadRequest)
+			return
+		}
+		if err := validateLevel(level); err != nil {
+			http.Error(w, fmt.Sprintf("scope %q: %s", scope, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, r := range c.SamplingRules {
+		if r.Scope == "" {
+			http.Error(w, "sampling rule scope must not be empty", http.StatusBadRequest)
+			return
+		}
+		if err := validateLevel(r.Level); err != nil || r.Level == log.LevelNone {
+			http.Error(w, fmt.Sprintf("sampling rule for scope %q: invalid level %q", r.Scope, r.Level), http.StatusBadRequest)
+			return
+		}
+	}
+
+	output := log.OutputSink{
+		Level:       c.Level,
+		ScopeLevels: c.ScopeLevels,
+	}
+	if c.Sampling != nil {
+		output.Sampling = &log.OutputSampling{
+			Initial:    c.Sampling.Initial,
+			Thereafter: c.Sampling.Thereafter,
+		}
+	}
+	if c.SamplingRules != nil {
+		output.SamplingRules = make([]log.OutputSamplingRule, 0, len(c.SamplingRules))
+		for _, r := range c.SamplingRules {
+			output.SamplingRules = append(output.SamplingRules, log.OutputSamplingRule(r))
+		}
+	}
+	if err := h.callbacks.UpdateOutput(output); err != nil {
+		// Configuration is only rejected if it is invalid, e.g. invalid scope patterns
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated := h.config()
+	h.logger.Info("configuration updated",
+		log.String("level", string(updated.Level)),
+		log.String("scopeLevels", fmt.Sprintf("%v", updated.ScopeLevels)),
+		log.String("remoteAddr", r.RemoteAddr))
+	writeJSON(w, updated)
+}
+
+// validateLevel returns an error if level is set and not one of the supported levels.
+func validateLevel(level log.Level) error {
+	switch log.Level(strings.ToLower(string(level))) {
+	case "", log.LevelDebug, log.LevelInfo, log.LevelWarn, log.LevelError, log.LevelNone:
+		return nil
+	}
+	return fmt.Errorf("invalid level %q", level)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
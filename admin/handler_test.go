@@ -0,0 +1,171 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sourcegraph/log"
+)
+
+var callbacks *log.PostInitCallbacks
+
+func TestMain(m *testing.M) {
+	os.Setenv(log.EnvLogLevel, "warn")
+	os.Setenv(log.EnvLogScopeLevel, "")
+	os.Setenv(log.EnvLogSamplingInitial, "100")
+	callbacks = log.Init(log.Resource{Name: "admin-test"}, log.NewMemorySink(log.MemorySink{}))
+	code := m.Run()
+	callbacks.Sync()
+	os.Exit(code)
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(callbacks)
+
+	t.Run("get config", func(t *testing.T) {
+		code, body := do(t, h, http.MethodGet, "/", "")
+		require.Equal(t, http.StatusOK, code)
+
+		var c Config
+		require.NoError(t, json.Unmarshal([]byte(body), &c))
+		assert.Equal(t, Config{
+			Level:         log.LevelWarn,
+			ScopeLevels:   map[string]log.Level{},
+			Sampling:      &Sampling{Initial: 100, Thereafter: 100},
+			SamplingRules: []SamplingRule{},
+			Sinks:         []string{"OutputSink", "MemorySink"},
+		}, c)
+	})
+
+	t.Run("put config", func(t *testing.T) {
+		code, body := do(t, h, http.MethodPut, "/", `{
+			"level": "info",
+			"scopeLevels": {"foo.bar": "debug"},
+			"sampling": {"initial": 0},
+			"samplingRules": [{"scope": "search.zoekt", "level": "debug", "initial": 1, "thereafter": 1000}]
+		}`)
+		require.Equal(t, http.StatusOK, code, body)
+
+		var c Config
+		require.NoError(t, json.Unmarshal([]byte(body), &c))
+		assert.Equal(t, Config{
+			Level:       log.LevelInfo,
+			ScopeLevels: map[string]log.Level{"foo.bar": log.LevelDebug},
+			Sampling:    &Sampling{Initial: 0, Thereafter: 0},
+			SamplingRules: []SamplingRule{
+				{Scope: "search.zoekt", Level: log.LevelDebug, Initial: 1, Thereafter: 1000},
+			},
+			Sinks: []string{"OutputSink", "MemorySink"},
+		}, c)
+
+		// Unset values are restored from the environment
+		code, body = do(t, h, http.MethodPut, "/", `{}`)
+		require.Equal(t, http.StatusOK, code, body)
+		c = Config{}
+		require.NoError(t, json.Unmarshal([]byte(body), &c))
+		assert.Equal(t, log.LevelWarn, c.Level)
+		assert.Empty(t, c.ScopeLevels)
+		assert.Equal(t, &Sampling{Initial: 100, Thereafter: 100}, c.Sampling)
+		assert.Empty(t, c.SamplingRules)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		for _, body := range []string{
+			`{"level": "verbose"}`,
+			`{"scopeLevels": {"foo": "verbose"}}`,
+			`{"scopeLevels": {"": "debug"}}`,
+			`{"scopeLevels": {"foo..bar": "debug"}}`,
+			`{"samplingRules": [{"scope": "", "initial": 1}]}`,
+			`{"samplingRules": [{"scope": "foo", "level": "none", "initial": 1}]}`,
+			`{"samplingRules": [{"scope": "/(/", "initial": 1}]}`,
+			`not json`,
+		} {
+			code, _ := do(t, h, http.MethodPut, "/", body)
+			assert.Equal(t, http.StatusBadRequest, code, body)
+		}
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		log.Scoped("admin-test-scope").Scoped("child")
+
+		code, body := do(t, h, http.MethodGet, "/scopes", "")
+		require.Equal(t, http.StatusOK, code)
+
+		var scopes []string
+		require.NoError(t, json.Unmarshal([]byte(body), &scopes))
+		assert.Contains(t, scopes, "admin-test-scope")
+		assert.Contains(t, scopes, "admin-test-scope.child")
+	})
+
+	t.Run("drops", func(t *testing.T) {
+		code, body := do(t, h, http.MethodPut, "/", `{"sampling": {"initial": 1}}`)
+		require.Equal(t, http.StatusOK, code, body)
+		logger := log.Scoped("admin-test-drops")
+		for i := 0; i < 3; i++ {
+			logger.Warn("dropped")
+		}
+
+		code, body = do(t, h, http.MethodGet, "/drops", "")
+		require.Equal(t, http.StatusOK, code)
+
+		var drops []SamplingDrop
+		require.NoError(t, json.Unmarshal([]byte(body), &drops))
+		assert.Contains(t, drops, SamplingDrop{
+			Scope:   "admin-test-drops",
+			Level:   log.LevelWarn,
+			Message: "dropped",
+			Dropped: 2,
+		})
+
+		code, body = do(t, h, http.MethodPut, "/", `{}`)
+		require.Equal(t, http.StatusOK, code, body)
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		logger := log.Scoped("admin-test-logs")
+		logger.Warn("first", log.String("user", "alice"))
+		logger.Error("second", log.String("user", "bob"))
+
+		code, body := do(t, h, http.MethodGet, "/logs?scope=admin-test-logs&field.user=bob", "")
+		require.Equal(t, http.StatusOK, code, body)
+
+		var entries []log.MemoryEntry
+		require.NoError(t, json.Unmarshal([]byte(body), &entries))
+		require.Len(t, entries, 1)
+		assert.Equal(t, "second", entries[0].Message)
+		assert.Equal(t, "error", entries[0].Level)
+
+		for _, query := range []string{"level=verbose", "since=yesterday", "limit=ten", "scope=foo..bar"} {
+			code, _ := do(t, h, http.MethodGet, "/logs?"+query, "")
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+
+	t.Run("sentry not enabled", func(t *testing.T) {
+		code, _ := do(t, h, http.MethodGet, "/sentry", "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		code, _ := do(t, h, http.MethodGet, "/foo", "")
+		assert.Equal(t, http.StatusNotFound, code)
+
+		code, _ = do(t, h, http.MethodDelete, "/", "")
+		assert.Equal(t, http.StatusMethodNotAllowed, code)
+	})
+}
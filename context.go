@@ -0,0 +1,181 @@
+package log
+
+import (
+	"context"
+)
+
+// contextKey is the key under which contextValues are stored on a context.Context.
+type contextKey struct{}
+
+// contextValues is the logging state carried on a context.Context.
+type contextValues struct {
+	// logger is the Logger attached with WithContext, if any.
+	logger Logger
+	// fields are attributes attached with WithFields, to be applied to every entry
+	// logged with the context.
+	fields []Field
+	// trace is the trace context attached with WithTraceContext, if any. It takes
+	// precedence over any OpenTelemetry span active in the context.
+	trace TraceContext
+}
+
+func valuesFromContext(ctx context.Context) contextValues {
+	if ctx == nil {
+		return contextValues{}
+	}
+	if v, ok := ctx.Value(contextKey{}).(contextValues); ok {
+		return v
+	}
+	return contextValues{}
+}
+
+// WithContext returns a copy of ctx carrying logger, which can be retrieved with
+// FromContext. Fields and trace context attached to ctx with WithFields and
+// WithTraceContext are retained, and are applied to the logger on retrieval.
+func WithContext(ctx context.Context, logger Logger) context.Context {
+	v := valuesFromContext(ctx)
+	v.logger = logger
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// WithFields returns a copy of ctx carrying the given fields as attributes, in addition
+// to any fields already attached to ctx. The fields are added to entries logged by the
+// Logger returned by FromContext, and by the context-taking log functions (e.g.
+// InfoCtx).
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	v := valuesFromContext(ctx)
+	// Always copy, so that sibling contexts never share a backing array.
+	merged := make([]Field, 0, len(v.fields)+len(fields))
+	merged = append(merged, v.fields...)
+	v.fields = append(merged, fields...)
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FieldsFromContext returns the fields attached to ctx with WithFields, if any. This is
+// useful for implementations of Logger that do not wrap a Logger from this package.
+func FieldsFromContext(ctx context.Context) []Field {
+	return valuesFromContext(ctx).fields
+}
+
+// WithTraceContext returns a copy of ctx carrying the given trace context, overwriting
+// any trace context previously attached to ctx. The trace context is added to entries
+// logged by the Logger returned by FromContext, and by the context-taking log functions
+// (e.g. InfoCtx).
+//
+// If TraceContext has no fields set, this function is a no-op.
+func WithTraceContext(ctx context.Context, trace TraceContext) context.Context {
+	if trace.TraceID == "" && trace.SpanID == "" {
+		return ctx
+	}
+	v := valuesFromContext(ctx)
+	v.trace = trace
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// TraceContextFromContext returns the trace context attached to ctx with
+// WithTraceContext. If none is attached, the trace context is derived from the
+// OpenTelemetry span active in ctx, if there is one.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	v := valuesFromContext(ctx)
+	if v.trace.TraceID != "" || v.trace.SpanID != "" {
+		return v.trace, true
+	}
+	return traceContextFromSpan(ctx)
+}
+
+// FromContext returns the Logger attached to ctx with WithContext, with any fields and
+// trace context carried by ctx applied (see TraceContextFromContext). If no Logger is
+// attached to ctx, a no-op Logger is returned - callers that must always produce output
+// should hold a reference to a Logger and use the context-taking log functions (e.g.
+// InfoCtx) with it instead.
+func FromContext(ctx context.Context) Logger {
+	logger := valuesFromContext(ctx).logger
+	if logger == nil {
+		logger = NoOp()
+	}
+	return applyContext(ctx, logger)
+}
+
+// applyContext adds the fields and trace context carried by ctx to logger.
+func applyContext(ctx context.Context, logger Logger) Logger {
+	if fields := FieldsFromContext(ctx); len(fields) > 0 {
+		logger = logger.With(fields...)
+	}
+	if trace, ok := TraceContextFromContext(ctx); ok {
+		logger = logger.WithTrace(trace)
+	}
+	return logger
+}
+
+// ContextLogger is an optional interface for Loggers that handle the context passed to
+// the context-taking log functions (e.g. InfoCtx) themselves, for example to pass it on
+// to another logging library. Implementations should include the fields and trace
+// context carried by ctx, and AddCallerSkip must also return a ContextLogger.
+type ContextLogger interface {
+	Logger
+
+	DebugCtx(ctx context.Context, msg string, fields ...Field)
+	InfoCtx(ctx context.Context, msg string, fields ...Field)
+	WarnCtx(ctx context.Context, msg string, fields ...Field)
+	ErrorCtx(ctx context.Context, msg string, fields ...Field)
+}
+
+// DebugCtx logs a debug message with logger like Logger.Debug, additionally including
+// any fields and trace context carried by ctx - see WithFields and
+// TraceContextFromContext.
+func DebugCtx(ctx context.Context, logger Logger, msg string, fields ...Field) {
+	if cl, ok := contextLogger(ctx, logger); ok {
+		cl.DebugCtx(ctx, msg, fields...)
+	} else {
+		applyContext(ctx, logger.AddCallerSkip(1)).Debug(msg, fields...)
+	}
+}
+
+// InfoCtx logs an info message with logger like Logger.Info, additionally including
+// any fields and trace context carried by ctx - see WithFields and
+// TraceContextFromContext.
+func InfoCtx(ctx context.Context, logger Logger, msg string, fields ...Field) {
+	if cl, ok := contextLogger(ctx, logger); ok {
+		cl.InfoCtx(ctx, msg, fields...)
+	} else {
+		applyContext(ctx, logger.AddCallerSkip(1)).Info(msg, fields...)
+	}
+}
+
+// WarnCtx logs a message at WarnLevel with logger like Logger.Warn, additionally
+// including any fields and trace context carried by ctx - see WithFields and
+// TraceContextFromContext.
+func WarnCtx(ctx context.Context, logger Logger, msg string, fields ...Field) {
+	if cl, ok := contextLogger(ctx, logger); ok {
+		cl.WarnCtx(ctx, msg, fields...)
+	} else {
+		applyContext(ctx, logger.AddCallerSkip(1)).Warn(msg, fields...)
+	}
+}
+
+// ErrorCtx logs an error message with logger like Logger.Error, additionally including
+// any fields and trace context carried by ctx - see WithFields and
+// TraceContextFromContext.
+func ErrorCtx(ctx context.Context, logger Logger, msg string, fields ...Field) {
+	if cl, ok := contextLogger(ctx, logger); ok {
+		cl.ErrorCtx(ctx, msg, fields...)
+	} else {
+		applyContext(ctx, logger.AddCallerSkip(1)).Error(msg, fields...)
+	}
+}
+
+// contextLogger returns logger as a ContextLogger that skips the context-taking log
+// function calling it, if logger implements ContextLogger and ctx is not nil.
+func contextLogger(ctx context.Context, logger Logger) (ContextLogger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	if _, ok := logger.(ContextLogger); !ok {
+		return nil, false
+	}
+	cl, ok := logger.AddCallerSkip(1).(ContextLogger)
+	return cl, ok
+}
@@ -0,0 +1,130 @@
+package log_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/log"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Run("no logger", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			log.FromContext(context.Background()).Info("should not panic")
+		})
+	})
+
+	t.Run("with logger, fields, and trace", func(t *testing.T) {
+		logger, exportLogs := newTestLogger(t)
+
+		ctx := log.WithFields(context.Background(), log.String("before", "logger"))
+		ctx = log.WithContext(ctx, logger)
+		ctx = log.WithFields(ctx, log.String("after", "logger"))
+		ctx = log.WithTraceContext(ctx, log.TraceContext{TraceID: "1234abcde"})
+
+		log.FromContext(ctx).Info("hello world", log.String("hello", "world"))
+
+		logs := exportLogs()
+		assert.Len(t, logs, 1)
+		assert.Equal(t, "TestFromContext/with_logger,_fields,_and_trace", logs[0].Scope)
+		assert.Equal(t, "1234abcde", logs[0].Fields["TraceId"])
+		assert.Equal(t, map[string]interface{}{
+			"before": "logger",
+			"after":  "logger",
+			"hello":  "world",
+		}, logs[0].Fields["Attributes"])
+	})
+}
+
+func TestLoggerCtx(t *testing.T) {
+	logger, exportLogs := newTestLogger(t)
+	logger = logger.With(log.String("some", "field"))
+
+	ctx := log.WithFields(context.Background(), log.String("request", "foo"))
+	log.InfoCtx(ctx, logger, "without trace") // 0
+
+	ctx = log.WithTraceContext(ctx, log.TraceContext{TraceID: "1"})
+	log.WarnCtx(ctx, logger, "with trace 1") // 1
+
+	ctx = log.WithTraceContext(ctx, log.TraceContext{TraceID: "2"})
+	log.ErrorCtx(ctx, logger, "with trace 2", log.String("extra", "value")) // 2
+
+	log.DebugCtx(context.Background(), logger, "plain context") // 3
+
+	logs := exportLogs()
+	assert.Len(t, logs, 4)
+
+	assert.NotContains(t, logs[0].Fields, "TraceId")
+	assert.Equal(t, map[string]interface{}{
+		"some":    "field",
+		"request": "foo",
+	}, logs[0].Fields["Attributes"])
+
+	assert.Equal(t, "1", logs[1].Fields["TraceId"])
+	assert.Equal(t, "2", logs[2].Fields["TraceId"])
+	assert.Equal(t, map[string]interface{}{
+		"some":    "field",
+		"request": "foo",
+		"extra":   "value",
+	}, logs[2].Fields["Attributes"])
+
+	assert.NotContains(t, logs[3].Fields, "TraceId")
+	assert.Equal(t, map[string]interface{}{
+		"some": "field",
+	}, logs[3].Fields["Attributes"])
+
+	// Context must not leak into the Logger itself
+	logger.Info("no context")
+	logs = exportLogs()
+	assert.NotContains(t, logs[0].Fields, "TraceId")
+}
+
+func TestLoggerCtxNilContext(t *testing.T) {
+	logger, exportLogs := newTestLogger(t)
+
+	var ctx context.Context
+	assert.NotPanics(t, func() {
+		log.InfoCtx(ctx, logger, "nil context")
+		log.FromContext(ctx).Info("no logger")
+	})
+	assert.Equal(t, []string{"nil context"}, exportLogs().Messages())
+}
+
+// ctxLogger is a ContextLogger that records the contexts it is given.
+type ctxLogger struct {
+	log.Logger
+	contexts *[]context.Context
+}
+
+func (l ctxLogger) AddCallerSkip(skip int) log.Logger {
+	return ctxLogger{Logger: l.Logger.AddCallerSkip(skip), contexts: l.contexts}
+}
+
+func (l ctxLogger) DebugCtx(ctx context.Context, msg string, fields ...log.Field) {
+	*l.contexts = append(*l.contexts, ctx)
+}
+
+func (l ctxLogger) InfoCtx(ctx context.Context, msg string, fields ...log.Field) {
+	*l.contexts = append(*l.contexts, ctx)
+}
+
+func (l ctxLogger) WarnCtx(ctx context.Context, msg string, fields ...log.Field) {
+	*l.contexts = append(*l.contexts, ctx)
+}
+
+func (l ctxLogger) ErrorCtx(ctx context.Context, msg string, fields ...log.Field) {
+	*l.contexts = append(*l.contexts, ctx)
+}
+
+func TestContextLogger(t *testing.T) {
+	var contexts []context.Context
+	logger := ctxLogger{Logger: log.NoOp(), contexts: &contexts}
+
+	ctx := log.WithFields(context.Background(), log.String("request", "foo"))
+	log.InfoCtx(ctx, logger, "handled by the logger")
+	log.ErrorCtx(ctx, logger, "handled by the logger")
+
+	assert.Equal(t, []context.Context{ctx, ctx}, contexts)
+}
@@ -6,5 +6,22 @@
 // available, disallowing the use of compile-time loggers, and re-exporting a limited
 // number of field types.
 //
+// # Context
+//
+// A Logger, request-scoped fields and trace context can be carried on a
+// context.Context with WithContext, WithFields and WithTraceContext, and retrieved with
+// FromContext. Functions that receive a Logger and a context can log with the
+// context-taking functions such as InfoCtx, which add the fields and trace context
+// carried by the context to the entry:
+//
+//	log.InfoCtx(ctx, logger, "handled request", log.Int("status", 200))
+//
+// These are package functions rather than methods on Logger, since adding methods to
+// the Logger interface would break every implementation of it outside this module,
+// such as test fakes and adapters to other logging libraries. Loggers that need the
+// context itself, for example to pass it on to another logging library, can implement
+// the optional ContextLogger interface, which the context-taking functions use when
+// available.
+//
 // Learn more: https://docs.sourcegraph.com/dev/how-to/add_logging
 package log
@@ -5,6 +5,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/sinkcores/sentrycore"
 )
 
 // A Field is a marshaling operation used to add a key-value pair to a logger's context.
@@ -125,6 +126,16 @@ func Object(key string, fields ...Field) Field {
 	return zap.Object(key, encoders.FieldsObjectEncoder(fields))
 }
 
+// SentryFingerprint constructs a field that sets the fingerprint used by the Sentry sink
+// to group reports of the log message into issues, overriding SentrySink.Grouping. The
+// "{{ default }}" element refers to Sentry's default grouping, which can be used to
+// further split it. Other sinks ignore this field.
+//
+// See https://docs.sentry.io/platforms/go/usage/sdk-fingerprinting/
+func SentryFingerprint(fingerprint ...string) Field {
+	return sentrycore.FingerprintField(fingerprint...)
+}
+
 // Error is shorthand for the common idiom NamedError("error", err).
 func Error(err error) Field {
 	return NamedError("error", err)
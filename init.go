@@ -31,12 +31,29 @@ var (
 	//
 	//  - these levels do not respect the root level (SRC_LOG_LEVEL), so this
 	//    allows operators to turn up or down the verbosity of specific logs.
-	//  - this only affects the outputcore (ie will not effect sentrycore).
+	//  - this only affects the output sink - use EnvLogSentryScopeLevel to
+	//    override the level at which the Sentry sink reports errors.
 	//  - Scope matches the full scope name. IE the below example has the scope
 	//    "foo.bar" not "bar".
 	//
 	//    log.Scoped("foo", "").Scoped("bar", "")
+	//
+	//  - Scope can also be a pattern. In globs, '*' matches a single segment of
+	//    a scope name and '**' matches any number of segments, e.g.
+	//    "*.httpapi=debug" or "gitserver.*.fetch=info". Regular expressions
+	//    enclosed in '/' match the full scope name, e.g. "/foo\.(bar|baz)/=debug",
+	//    and may not contain ','.
+	//  - If multiple scopes match, the most specific one is used: the one that
+	//    matches the most segments of the scope name, not counting segments
+	//    matched by '**', then the one with the most segments without wildcards.
+	//    Regular expressions are only used if no scope name or glob matches.
 	EnvLogScopeLevel = "SRC_LOG_SCOPE_LEVEL"
+	// EnvLogSentryScopeLevel is key of the environment variable that can be used to
+	// override the level at which entries with errors are reported by the Sentry sink
+	// for specific scopes and its children, which is 'error' by default.
+	//
+	// It has the same format as EnvLogScopeLevel, e.g. "foo.bar=warn,noisy=none".
+	EnvLogSentryScopeLevel = "SRC_LOG_SENTRY_SCOPE_LEVEL"
 	// EnvLogSamplingInitial is key of the environment variable that can be used to set
 	// the number of entries with identical messages to always output per second.
 	//
@@ -48,6 +65,57 @@ var (
 	//
 	// Defaults to 100 - set explicitly to 0 or -1 to disable.
 	EnvLogSamplingThereafter = "SRC_LOG_SAMPLING_THEREAFTER"
+	// EnvLogSamplingRules is key of the environment variable that can be used to
+	// override the sampling configuration for entries from specific scopes and levels.
+	//
+	// It has the format "SCOPE_0[:LEVEL_0]=INITIAL_0[/THEREAFTER_0],...". For example,
+	// "**:error=0,search.zoekt:debug=1/1000" never samples errors, and only outputs
+	// the first and then every 1000th debug entry with the same message from the
+	// "search.zoekt" scope per second. If THEREAFTER is omitted, only the first INITIAL
+	// entries are output per second.
+	//
+	// Notes:
+	//
+	//  - SCOPE is a pattern as described in EnvLogScopeLevel. If multiple rules match,
+	//    the rule with the most specific scope is used, preferring rules with a LEVEL.
+	//  - Entries are counted per scope, so entries with the same message from
+	//    different scopes are sampled independently.
+	EnvLogSamplingRules = "SRC_LOG_SAMPLING_RULES"
+	// EnvLogDedup is key of the environment variable that can be used to collapse
+	// duplicate entries in the output sink, i.e. entries with the same scope, level,
+	// message and fields, for example from retry loops.
+	//
+	// The first entry is always output. Any duplicates are then output as a single
+	// entry with the fields "repeated", "firstSeen" and "lastSeen", which is output
+	// when the next entry is logged after the window expires, or on Sync.
+	//
+	// The value is one of:
+	//
+	//  - 'off', the default, to disable deduplication.
+	//  - 'consecutive', to only collapse duplicates that are not interleaved with
+	//    other entries, within a window of 1 minute.
+	//  - a duration such as '10s', to collapse all duplicates within the given
+	//    window after an entry.
+	EnvLogDedup = "SRC_LOG_DEDUP"
+	// EnvLogFlightRecorder is key of the environment variable that can be used to
+	// buffer entries that are below the level of the output sink, and only output them
+	// when an error is logged - giving full context around failures without always
+	// paying for verbose output.
+	//
+	// Entries are buffered per trace (see Logger.WithTrace), or per scope for entries
+	// without a trace. When an entry at the error level or above is output, the entries
+	// buffered for its trace or scope are output first, with the field "flightRecorder"
+	// set to true.
+	//
+	// The value is the lowest level to buffer, one of 'debug', 'info' or 'warn', and
+	// defaults to 'none' which disables buffering.
+	EnvLogFlightRecorder = "SRC_LOG_FLIGHT_RECORDER"
+	// EnvLogFlightRecorderSize is key of the environment variable that can be used to
+	// set the number of entries buffered per trace or scope with EnvLogFlightRecorder.
+	// Older entries are discarded when the buffer is full.
+	//
+	// Defaults to 100.
+	EnvLogFlightRecorderSize = "SRC_LOG_FLIGHT_RECORDER_SIZE"
 )
 
 type Resource = otelfields.Resource
@@ -55,25 +123,110 @@ type Resource = otelfields.Resource
 // PostInitCallbacks is a set of callbacks returned by Init that enables finalization and
 // updating of any configured sinks.
 type PostInitCallbacks struct {
-	// Sync must be called before application exit, such as via defer.
+	// Sync must be called before application exit, such as via defer. It flushes all
+	// sinks, which keep working afterwards. Use Close instead to also release sinks.
 	//
 	// Note: The error from sync is suppressed since this is usually called as a
 	// defer in func main. In that case there isn't a reasonable way to handle the
 	// error. As such this function signature doesn't return an error.
 	Sync func()
 
+	// Close flushes all sinks like Sync, and then releases resources held by sinks,
+	// such as files, connections and background goroutines - for example, custom sinks
+	// that implement io.Closer are closed. Entries logged to such sinks after Close are
+	// lost, so Close should only be called on shutdown, in place of Sync.
+	Close func()
+
 	// Update should be called to change sink configuration, e.g. via
-	// conf.Watch. Note that sinks not created upon initialization will
-	// not be created post-initialization. Is a no-op if no sinks are enabled.
+	// conf.Watch. This includes the levels of the output sink, see
+	// SinksConfig.Output. Note that sinks not created upon initialization will
+	// not be created post-initialization.
 	Update func(SinksConfigGetter) func()
+
+	sinks  sinks
+	output *outputSink
+}
+
+// SinkNames returns the names of all sinks that are enabled.
+func (c *PostInitCallbacks) SinkNames() []string {
+	names := make([]string, len(c.sinks))
+	for i, s := range c.sinks {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// OutputConfig returns the current configuration of the output sink, with all values
+// populated, including those from the environment.
+func (c *PostInitCallbacks) OutputConfig() OutputSink {
+	return c.output.config()
+}
+
+// MemoryLogs returns the memory sink provided to Init, or nil if no memory sink was
+// provided. See NewMemorySink.
+func (c *PostInitCallbacks) MemoryLogs() *MemoryLogs {
+	for _, s := range c.sinks {
+		if m, ok := s.(*MemoryLogs); ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// SamplingDrops returns the cumulative number of entries dropped by sampling in the
+// output sink since initialization, most dropped first. Summaries of dropped entries
+// are also logged periodically by the output sink.
+func (c *PostInitCallbacks) SamplingDrops() []SamplingDrop {
+	return c.output.samplingDrops()
+}
+
+// SentryStats returns the cumulative counts of reports handled by the Sentry sink since
+// initialization, or false if no Sentry sink was provided. See SentrySink.StatsInterval
+// to log them periodically instead.
+func (c *PostInitCallbacks) SentryStats() (SentryStats, bool) {
+	for _, s := range c.sinks {
+		if sentry, ok := s.(*sentrySink); ok && sentry.core != nil {
+			return sentry.stats(), true
+		}
+	}
+	return SentryStats{}, false
+}
+
+// HTTPStats returns the cumulative counts of entries that the HTTP sink did not send
+// since initialization, or false if no HTTP sink was provided.
+func (c *PostInitCallbacks) HTTPStats() (HTTPStats, bool) {
+	for _, s := range c.sinks {
+		if http, ok := s.(*httpSink); ok && http.writer != nil {
+			return http.stats(), true
+		}
+	}
+	return HTTPStats{}, false
+}
+
+// SyslogStats returns the cumulative counts of entries that the syslog sink did not
+// send since initialization, or false if no syslog sink was provided.
+func (c *PostInitCallbacks) SyslogStats() (SyslogStats, bool) {
+	for _, s := range c.sinks {
+		if syslog, ok := s.(*syslogSink); ok && syslog.conn != nil {
+			return syslog.stats(), true
+		}
+	}
+	return SyslogStats{}, false
+}
+
+// UpdateOutput changes the configuration of the output sink only, leaving other sinks
+// unchanged. Unset values in the configuration are restored from the environment, as
+// with SinksConfig.Output in Update.
+func (c *PostInitCallbacks) UpdateOutput(config OutputSink) error {
+	return c.output.update(SinksConfig{Output: &config})
 }
 
 // Init initializes the log package's global logger as a logger of the given resource.
 // It must be called on service startup, i.e. 'main()', NOT on an 'init()' function.
 // Subsequent calls will panic, so do not call this within a non-service context.
 //
-// Init returns a set of callbacks - see PostInitCallbacks for more details. The Sync
-// callback in particular must be called before application exit.
+// Init returns a set of callbacks - see PostInitCallbacks for more details. The Sync or
+// Close callback in particular must be called before application exit.
 //
 // For testing, you can use 'logtest.Init' to initialize the logging library.
 //
@@ -89,7 +242,8 @@ func Init(r Resource, s ...Sink) *PostInitCallbacks {
 	currentDevMode := os.Getenv(globallogger.EnvDevelopment) == "true"
 
 	// Initialize sinks
-	ss := sinks(append([]Sink{&outputSink{development: currentDevMode}}, s...))
+	output := &outputSink{development: currentDevMode}
+	ss := sinks(append([]Sink{output}, s...))
 	cores, sinksBuildErr := ss.build()
 
 	// Init the logger first, so that we can log the error if needed, before dealing with
@@ -103,7 +257,13 @@ func Init(r Resource, s ...Sink) *PostInitCallbacks {
 	}
 
 	return &PostInitCallbacks{
-		Sync:   func() { _ = sync() },
+		Sync: func() { _ = sync() },
+		Close: func() {
+			_ = sync()
+			ss.close()
+		},
 		Update: ss.update,
+		sinks:  ss,
+		output: output,
 	}
 }
@@ -42,6 +42,9 @@ func (t *TraceContextEncoder) MarshalLogObject(enc zapcore.ObjectEncoder) error
 	if len(t.SpanID) > 0 {
 		enc.AddString("SpanId", t.SpanID)
 	}
+	if t.TraceFlags != 0 {
+		enc.AddString("TraceFlags", t.TraceFlags.String())
+	}
 	return nil
 }
 
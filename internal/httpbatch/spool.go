@@ -0,0 +1,104 @@
+package httpbatch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// spoolSuffix is the extension of spooled batch files.
+const spoolSuffix = ".batch"
+
+// spool persists batches that could not be delivered to a directory, so that they can
+// be retried later, including by another process after a restart. Batches are retried
+// in the order they were spooled.
+//
+// spool is not safe for concurrent use.
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	// files are the names of spooled batches, oldest first.
+	files []spoolFile
+	size  int64
+	seq   int
+}
+
+type spoolFile struct {
+	name string
+	size int64
+}
+
+// openSpool opens the spool in dir, creating dir if it does not exist and picking up
+// any batches spooled by a previous process.
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &spool{dir: dir, maxBytes: maxBytes}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		s.files = append(s.files, spoolFile{name: e.Name(), size: info.Size()})
+		s.size += info.Size()
+	}
+	// Names are ordered by the time they were spooled.
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].name < s.files[j].name })
+	return s, nil
+}
+
+func (s *spool) empty() bool { return len(s.files) == 0 }
+
+// add persists batch. If the spool would exceed its maximum size, the oldest batches
+// are removed to make room.
+func (s *spool) add(batch []byte) error {
+	if s.maxBytes > 0 && int64(len(batch)) > s.maxBytes {
+		return fmt.Errorf("batch of %d bytes exceeds maximum spool size", len(batch))
+	}
+	for s.maxBytes > 0 && s.size+int64(len(batch)) > s.maxBytes && len(s.files) > 0 {
+		s.remove()
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1000000, spoolSuffix)
+	// Write to a temporary file first, so that partially written batches are never
+	// picked up.
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, batch, 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	s.files = append(s.files, spoolFile{name: name, size: int64(len(batch))})
+	s.size += int64(len(batch))
+	return nil
+}
+
+// oldest returns the contents of the oldest spooled batch.
+func (s *spool) oldest() ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, s.files[0].name))
+}
+
+// remove removes the oldest spooled batch.
+func (s *spool) remove() {
+	_ = os.Remove(filepath.Join(s.dir, s.files[0].name))
+	s.size -= s.files[0].size
+	s.files = s.files[1:]
+}
@@ -0,0 +1,471 @@
+// Package httpbatch provides a writer that sends batches of encoded entries to an HTTP
+// endpoint.
+package httpbatch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// initialBackoff is the delay before the first retry of a failed request.
+	initialBackoff = 500 * time.Millisecond
+	// maxBackoff is the maximum delay between retries of a failed request.
+	maxBackoff = 30 * time.Second
+)
+
+// Options configures a Writer.
+type Options struct {
+	// URL is the endpoint to POST batches to.
+	URL string
+	// ContentType is the Content-Type of each request.
+	ContentType string
+	// Headers are sent with each request.
+	Headers map[string]string
+
+	// MaxBatchEntries is the maximum number of entries in a batch.
+	MaxBatchEntries int
+	// MaxBatchBytes is the maximum size of a batch in bytes. A batch is sent as soon as
+	// it reaches this size, so a single entry larger than this is sent on its own.
+	MaxBatchBytes int
+	// BatchTimeout is the maximum duration to wait after the first entry in a batch was
+	// written before sending the batch, if it is not full.
+	BatchTimeout time.Duration
+	// MaxBufferBytes is the maximum size of entries held in memory waiting to be sent.
+	// Entries written while the buffer is full are dropped.
+	MaxBufferBytes int
+
+	// RequestTimeout is the timeout of each request.
+	RequestTimeout time.Duration
+	// MaxElapsedTime is the maximum duration to spend retrying a batch before dropping
+	// it. It is not used if SpoolDir is set.
+	MaxElapsedTime time.Duration
+
+	// SpoolDir, if set, is a directory that batches which could not be sent are
+	// persisted to, and retried from until they are sent. Batches spooled by a previous
+	// process are picked up when the Writer is opened. The directory must not be shared
+	// with other Writers.
+	SpoolDir string
+	// MaxSpoolBytes is the maximum size of SpoolDir. If the spool would exceed this, the
+	// oldest batches are dropped. If zero, the spool is unbounded.
+	MaxSpoolBytes int64
+}
+
+// Writer is a zapcore.WriteSyncer that buffers written entries and sends them in
+// batches with HTTP POST requests from a background goroutine. Each batch is the
+// concatenation of the entries in it - for example, newline-delimited JSON if the
+// entries are encoded as JSON lines.
+//
+// Failed requests are retried with exponential backoff if the failure is transient,
+// i.e. network errors, 429 and 5xx responses.
+type Writer struct {
+	opts   Options
+	client *http.Client
+	// spool is nil if spooling is disabled.
+	spool *spool
+
+	mu sync.Mutex
+	// current is the batch being filled, and currentStart is when its first entry was
+	// written.
+	current        bytes.Buffer
+	currentEntries int
+	currentStart   time.Time
+	// batches are full batches waiting to be sent.
+	batches [][]byte
+	// buffered is the size of current and batches.
+	buffered int
+	closed   bool
+
+	// dropped is the number of entries dropped because the buffer was full.
+	dropped atomic.Uint64
+
+	// notify signals the background goroutine that a batch is ready, or that a new batch
+	// was started.
+	notify chan struct{}
+	// flushC receives requests to send all buffered entries, which are acknowledged by
+	// closing the received channel.
+	flushC chan chan struct{}
+
+	// ctx is cancelled when Close times out, to abort any retries in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// nextRetry is when spooled batches should next be retried, and backoff is the
+	// delay before the retry after that. Both are only used by the background goroutine.
+	nextRetry time.Time
+	backoff   time.Duration
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+var _ zapcore.WriteSyncer = &Writer{}
+var _ io.Closer = &Writer{}
+
+// Open creates a Writer and starts sending batches in the background.
+func Open(opts Options) (*Writer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer{
+		opts:    opts,
+		client:  &http.Client{},
+		notify:  make(chan struct{}, 1),
+		flushC:  make(chan chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		backoff: initialBackoff,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if opts.SpoolDir != "" {
+		s, err := openSpool(opts.SpoolDir, opts.MaxSpoolBytes)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		w.spool = s
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Write buffers a single encoded entry. If the buffer is full, the entry is dropped.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.buffered+len(p) > w.opts.MaxBufferBytes {
+		w.dropped.Add(1)
+		return len(p), nil // drop the entry
+	}
+
+	started := w.currentEntries == 0
+	if started {
+		w.currentStart = time.Now()
+	}
+	w.current.Write(p)
+	w.currentEntries++
+	w.buffered += len(p)
+	full := w.currentEntries >= w.opts.MaxBatchEntries || w.current.Len() >= w.opts.MaxBatchBytes
+	if full {
+		w.cutLocked()
+	}
+	if started || full {
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of entries dropped because the buffer was full.
+func (w *Writer) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// cutLocked moves the current batch to the queue of batches to send. It must be called
+// with w.mu held.
+func (w *Writer) cutLocked() {
+	if w.currentEntries == 0 {
+		return
+	}
+	batch := make([]byte, w.current.Len())
+	copy(batch, w.current.Bytes())
+	w.batches = append(w.batches, batch)
+	w.current.Reset()
+	w.currentEntries = 0
+}
+
+// next returns the next batch to send, if any, cutting the current batch first if
+// cut is true or BatchTimeout has elapsed since its first entry was written.
+func (w *Writer) next(cut bool) ([]byte, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cut || (w.currentEntries > 0 && time.Since(w.currentStart) >= w.opts.BatchTimeout) {
+		w.cutLocked()
+	}
+	if len(w.batches) == 0 {
+		return nil, false
+	}
+	batch := w.batches[0]
+	w.batches = w.batches[1:]
+	return batch, true
+}
+
+// release frees the buffer space held by a sent or dropped batch.
+func (w *Writer) release(batch []byte) {
+	w.mu.Lock()
+	w.buffered -= len(batch)
+	w.mu.Unlock()
+}
+
+// Sync blocks until all buffered entries have been sent, spooled or dropped, or until
+// RequestTimeout elapses.
+func (w *Writer) Sync() error {
+	ack := make(chan struct{})
+	timeout := time.NewTimer(w.opts.RequestTimeout)
+	defer timeout.Stop()
+
+	select {
+	case w.flushC <- ack:
+	case <-w.stopped:
+		return nil
+	case <-timeout.C:
+		return errors.New("timed out waiting to flush")
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-timeout.C:
+		return errors.New("timed out waiting to flush")
+	}
+}
+
+// Close sends all buffered entries and stops the Writer. If this does not complete
+// within RequestTimeout, any request in progress is aborted.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.closeOnce.Do(func() { close(w.done) })
+
+	var err error
+	select {
+	case <-w.stopped:
+	case <-time.After(w.opts.RequestTimeout):
+		err = errors.New("timed out waiting to flush")
+	}
+	w.cancel()
+	<-w.stopped
+	return err
+}
+
+// currentDeadline returns when the current batch is due to be sent, if it has entries.
+func (w *Writer) currentDeadline() (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.currentEntries == 0 {
+		return time.Time{}, false
+	}
+	return w.currentStart.Add(w.opts.BatchTimeout), true
+}
+
+func (w *Writer) run() {
+	defer close(w.stopped)
+
+	// timer fires when the current batch is due, and retryC when spooled batches may be
+	// due for a retry.
+	timer := time.NewTimer(w.opts.BatchTimeout)
+	defer timer.Stop()
+	var retryC <-chan time.Time
+	if w.spool != nil {
+		retryTicker := time.NewTicker(initialBackoff)
+		defer retryTicker.Stop()
+		retryC = retryTicker.C
+	}
+
+	for {
+		select {
+		case <-w.notify:
+		case <-timer.C:
+		case <-retryC:
+		case ack := <-w.flushC:
+			w.sendAll(true)
+			w.retrySpool(true)
+			close(ack)
+			continue
+		case <-w.done:
+			w.sendAll(true)
+			return
+		}
+		w.sendAll(false)
+		w.retrySpool(false)
+
+		// Wait for the current batch to become due, or for a new batch to be started.
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if deadline, ok := w.currentDeadline(); ok {
+			timer.Reset(time.Until(deadline))
+		}
+	}
+}
+
+// sendAll sends all queued batches.
+func (w *Writer) sendAll(cut bool) {
+	for {
+		batch, ok := w.next(cut)
+		if !ok {
+			return
+		}
+		w.deliver(batch)
+		w.release(batch)
+	}
+}
+
+// deliver sends the batch. If spooling is enabled, batches are spooled instead if they
+// cannot be sent, or if older batches are spooled already. Otherwise, the batch is
+// retried until MaxElapsedTime elapses.
+func (w *Writer) deliver(batch []byte) {
+	if w.spool == nil {
+		w.sendWithRetry(batch)
+		return
+	}
+
+	if w.spool.empty() {
+		err := w.send(batch)
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) {
+			return // sent, or permanently rejected
+		}
+		w.scheduleRetry(retryable)
+	}
+	// If the batch could not be spooled, there is nothing more we can do.
+	_ = w.spool.add(batch)
+}
+
+// retrySpool sends spooled batches, oldest first, until one fails. If force is false,
+// spooled batches are only retried once the backoff after the last failure elapses.
+func (w *Writer) retrySpool(force bool) {
+	if w.spool == nil {
+		return
+	}
+	if !force && time.Now().Before(w.nextRetry) {
+		return
+	}
+
+	for !w.spool.empty() {
+		if w.ctx.Err() != nil {
+			return
+		}
+		batch, err := w.spool.oldest()
+		if err != nil {
+			w.spool.remove() // unreadable, drop it
+			continue
+		}
+		err = w.send(batch)
+		var retryable *retryableError
+		if errors.As(err, &retryable) {
+			w.scheduleRetry(retryable)
+			return
+		}
+		// Sent, or permanently rejected
+		w.spool.remove()
+	}
+	w.backoff = initialBackoff
+}
+
+func (w *Writer) scheduleRetry(err *retryableError) {
+	wait := w.backoff
+	if err.after > 0 {
+		wait = err.after
+	}
+	w.nextRetry = time.Now().Add(wait)
+	w.backoff *= 2
+	if w.backoff > maxBackoff {
+		w.backoff = maxBackoff
+	}
+}
+
+// sendWithRetry sends the batch, retrying with exponential backoff until
+// MaxElapsedTime has elapsed. Batches that cannot be sent are dropped.
+func (w *Writer) sendWithRetry(batch []byte) {
+	deadline := time.Now().Add(w.opts.MaxElapsedTime)
+	backoff := initialBackoff
+	for {
+		err := w.send(batch)
+		if err == nil {
+			return
+		}
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			return // permanent failure
+		}
+		wait := backoff
+		if retryable.after > 0 {
+			wait = retryable.after
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-w.ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// retryableError indicates that a request failed with a transient error and may be
+// retried.
+type retryableError struct {
+	err error
+	// after is the delay requested by the server before retrying, if any.
+	after time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+// send makes a single request with batch as the body.
+func (w *Writer) send(batch []byte) error {
+	ctx, cancel := context.WithTimeout(w.ctx, w.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	if w.opts.ContentType != "" {
+		req.Header.Set("Content-Type", w.opts.ContentType)
+	}
+	for k, v := range w.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("request failed with status %s", resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		retryErr := &retryableError{err: err}
+		if s, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && s > 0 {
+			retryErr.after = time.Duration(s) * time.Second
+		}
+		return retryErr
+	}
+	return err
+}
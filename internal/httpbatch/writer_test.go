@@ -0,0 +1,207 @@
+package httpbatch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer records the bodies of requests it receives.
+type testServer struct {
+	mu     sync.Mutex
+	bodies []string
+	// status is the status to respond with, defaulting to 200.
+	status int
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	s.bodies = append(s.bodies, r.Header.Get("Content-Type")+" "+string(body))
+}
+
+func (s *testServer) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *testServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func newTestWriter(t *testing.T, opts Options) (*Writer, *testServer) {
+	t.Helper()
+	s := &testServer{}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	opts.URL = srv.URL
+	opts.ContentType = "application/x-ndjson"
+	if opts.MaxBatchEntries == 0 {
+		opts.MaxBatchEntries = 2
+	}
+	if opts.MaxBatchBytes == 0 {
+		opts.MaxBatchBytes = 1 << 20
+	}
+	if opts.BatchTimeout == 0 {
+		opts.BatchTimeout = time.Hour
+	}
+	if opts.MaxBufferBytes == 0 {
+		opts.MaxBufferBytes = 1 << 20
+	}
+	opts.RequestTimeout = 5 * time.Second
+	if opts.MaxElapsedTime == 0 {
+		opts.MaxElapsedTime = time.Minute
+	}
+
+	w, err := Open(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	return w, s
+}
+
+func write(t *testing.T, w *Writer, lines ...string) {
+	t.Helper()
+	for _, l := range lines {
+		_, err := w.Write([]byte(l + "\n"))
+		require.NoError(t, err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("batches by entries", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{})
+		write(t, w, "a", "b", "c")
+		require.NoError(t, w.Sync())
+		assert.Equal(t, []string{
+			"application/x-ndjson a\nb\n",
+			"application/x-ndjson c\n",
+		}, s.received())
+	})
+
+	t.Run("batches by bytes", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{MaxBatchEntries: 100, MaxBatchBytes: 4})
+		write(t, w, "a", "b", "c")
+		require.NoError(t, w.Sync())
+		assert.Len(t, s.received(), 2)
+	})
+
+	t.Run("batches by time", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{MaxBatchEntries: 100, BatchTimeout: 10 * time.Millisecond})
+		write(t, w, "a")
+		assert.Eventually(t, func() bool { return len(s.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("batch timeout starts with the first entry", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{MaxBatchEntries: 100, BatchTimeout: 200 * time.Millisecond})
+		time.Sleep(150 * time.Millisecond)
+		write(t, w, "a")
+		assert.Never(t, func() bool { return len(s.received()) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+		write(t, w, "b")
+		assert.Eventually(t, func() bool { return len(s.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"application/x-ndjson a\nb\n"}, s.received())
+	})
+
+	t.Run("drops entries when buffer is full", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{MaxBatchEntries: 100, MaxBufferBytes: 4})
+		write(t, w, "a", "b", "c")
+		require.NoError(t, w.Sync())
+		assert.Equal(t, []string{"application/x-ndjson a\nb\n"}, s.received())
+		assert.Equal(t, uint64(1), w.Dropped())
+	})
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{})
+		s.setStatus(http.StatusServiceUnavailable)
+		time.AfterFunc(100*time.Millisecond, func() { s.setStatus(0) })
+		write(t, w, "a", "b")
+		assert.Eventually(t, func() bool { return len(s.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("drops rejected batches", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{})
+		s.setStatus(http.StatusBadRequest)
+		write(t, w, "a", "b")
+		require.NoError(t, w.Sync())
+		s.setStatus(0)
+		write(t, w, "c")
+		require.NoError(t, w.Sync())
+		assert.Equal(t, []string{"application/x-ndjson c\n"}, s.received())
+	})
+
+	t.Run("write after close", func(t *testing.T) {
+		w, s := newTestWriter(t, Options{})
+		write(t, w, "a")
+		require.NoError(t, w.Close())
+		assert.Len(t, s.received(), 1)
+
+		_, err := w.Write([]byte("b\n"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	})
+}
+
+func TestWriterSpool(t *testing.T) {
+	dir := t.TempDir()
+
+	// Collector is down - batches are spooled.
+	w, s := newTestWriter(t, Options{SpoolDir: dir})
+	s.setStatus(http.StatusServiceUnavailable)
+	write(t, w, "a", "b", "c")
+	require.NoError(t, w.Sync())
+	require.NoError(t, w.Close())
+	assert.Empty(t, s.received())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	// A new process picks up spooled batches, and sends them in order before new ones.
+	w, s = newTestWriter(t, Options{SpoolDir: dir})
+	write(t, w, "d")
+	require.NoError(t, w.Sync())
+	assert.Equal(t, []string{
+		"application/x-ndjson a\nb\n",
+		"application/x-ndjson c\n",
+		"application/x-ndjson d\n",
+	}, s.received())
+
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestSpoolMaxBytes(t *testing.T) {
+	s, err := openSpool(t.TempDir(), 8)
+	require.NoError(t, err)
+
+	require.NoError(t, s.add([]byte("aaaa")))
+	require.NoError(t, s.add([]byte("bbbb")))
+	// Oldest batch is dropped to make room
+	require.NoError(t, s.add([]byte("cccc")))
+	assert.Error(t, s.add([]byte(strings.Repeat("d", 9))))
+
+	var got []string
+	for !s.empty() {
+		b, err := s.oldest()
+		require.NoError(t, err)
+		got = append(got, string(b))
+		s.remove()
+	}
+	assert.Equal(t, []string{"bbbb", "cccc"}, got)
+}
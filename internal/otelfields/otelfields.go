@@ -1,6 +1,10 @@
 package otelfields
 
-import "go.uber.org/zap"
+import (
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
 
 const (
 	// ResourceFieldKey is the key used to identify Resource in stores.
@@ -35,8 +39,28 @@ type Resource struct {
 type TraceContext struct {
 	TraceID string
 	SpanID  string
+	// TraceFlags are the W3C trace flags of the trace. Optional, and only logged if
+	// any flags are set.
+	TraceFlags TraceFlags
+	// TraceState is the W3C tracestate propagated alongside the trace. It is not logged,
+	// and is only retained so that it can be propagated further.
+	TraceState string
 }
 
+// TraceFlags represents W3C trace flags.
+//
+// https://www.w3.org/TR/trace-context/#trace-flags
+type TraceFlags byte
+
+// TraceFlagsSampled indicates that the caller may have recorded trace data.
+const TraceFlagsSampled TraceFlags = 0x01
+
+// IsSampled indicates if the sampled flag is set.
+func (f TraceFlags) IsSampled() bool { return f&TraceFlagsSampled == TraceFlagsSampled }
+
+// String renders the flags as a two-character hex string, e.g. "01".
+func (f TraceFlags) String() string { return hex.EncodeToString([]byte{byte(f)}) }
+
 // attributesNamespace is the namespace under which all arbitrary fields are logged, as
 // per the OpenTelemetry spec.
 //
@@ -0,0 +1,347 @@
+// Package rotate provides a file writer that rotates files by size and age.
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// backupTimeFormat is the timestamp format used in the names of rotated files.
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	// compressSuffix is appended to the names of compressed rotated files.
+	compressSuffix = ".gz"
+)
+
+// Options configures rotation for a Writer.
+type Options struct {
+	// MaxSize is the size in bytes after which the file is rotated. If zero, files are
+	// not rotated based on size.
+	MaxSize int64
+	// MaxAge is the duration after which the file is rotated, measured from when the
+	// file was opened. If zero, files are not rotated based on age.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to retain. If zero, all rotated files
+	// are retained.
+	MaxBackups int
+	// Compress indicates if rotated files should be compressed with gzip.
+	Compress bool
+}
+
+// Writer is a zapcore.WriteSyncer that writes to a file, rotating it when it exceeds the
+// configured size or age. Rotation is only checked on Write.
+//
+// Rotated files are renamed to include the time of rotation, e.g. 'foo.log' is rotated
+// to 'foo-2006-01-02T15-04-05.000.log'. Compression and removal of old rotated files
+// happens in the background.
+type Writer struct {
+	path string
+	opts Options
+
+	// now is used to get the current time, and can be replaced in tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	// millCh signals the background goroutine to compress and clean up rotated files.
+	millCh   chan struct{}
+	millDone chan struct{}
+}
+
+var _ zapcore.WriteSyncer = &Writer{}
+var _ io.Closer = &Writer{}
+
+// Open opens a Writer for the file at path, creating it and its parent directories if
+// they do not exist. Entries are appended to the file if it already exists.
+func Open(path string, opts Options) (*Writer, error) {
+	return open(path, opts, time.Now)
+}
+
+func open(path string, opts Options, now func() time.Time) (*Writer, error) {
+	w := &Writer{
+		path:     path,
+		opts:     opts,
+		now:      now,
+		millCh:   make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	if err := w.openFile(); err != nil {
+		return nil, err
+	}
+	go w.mill()
+	return w, nil
+}
+
+// openFile opens the file at w.path for appending. w.mu must be held or unused.
+func (w *Writer) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("creating log directory: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = w.now()
+	return nil
+}
+
+// Write writes p to the file, rotating the file first if needed.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	var rotateErr error
+	if w.shouldRotate(int64(len(p))) {
+		// If rotation fails, the current file is kept, so write to it anyway rather
+		// than losing the entry. Rotation is attempted again on the next write.
+		rotateErr = w.rotate()
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+func (w *Writer) shouldRotate(incoming int64) bool {
+	// Never rotate an empty file, to avoid creating empty rotated files when a single
+	// write exceeds MaxSize.
+	if w.size == 0 {
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size+incoming > w.opts.MaxSize {
+		return true
+	}
+	if w.opts.MaxAge > 0 && w.now().Sub(w.openedAt) >= w.opts.MaxAge {
+		return true
+	}
+	return false
+}
+
+// rotate moves the current file aside, opens a new file, and closes the current file.
+// If rotation fails, the current file is kept open at its original path. w.mu must be
+// held.
+func (w *Writer) rotate() error {
+	// Move the file aside while it is still open, so that it remains usable if a new
+	// file cannot be opened.
+	backup := w.backupName(w.now())
+	if err := os.Rename(w.path, backup); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("rotating log file: %w", err)
+		}
+		// The file was removed by someone else, so there is nothing to move aside
+		backup = ""
+	}
+	current := w.file
+	if err := w.openFile(); err != nil {
+		if backup == "" {
+			return err
+		}
+		// Move the file back, so that it is rotated on the next attempt
+		if rerr := os.Rename(backup, w.path); rerr != nil {
+			return fmt.Errorf("%w (restoring log file: %v)", err, rerr)
+		}
+		return err
+	}
+	if err := current.Close(); err != nil {
+		return fmt.Errorf("closing rotated log file: %w", err)
+	}
+
+	select {
+	case w.millCh <- struct{}{}:
+	default: // already signalled
+	}
+	return nil
+}
+
+// backupName returns the name of the file rotated at t. Names only have millisecond
+// resolution, so if a file rotated in the same millisecond exists, the time is advanced
+// until the name is unused, which keeps rotated files in order.
+func (w *Writer) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	for {
+		name := filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+		if !exists(name) && !exists(name+compressSuffix) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
+
+// nameParts returns the directory of the file, and the prefix and extension of the
+// names of rotated files.
+func (w *Writer) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.path)
+	base := filepath.Base(w.path)
+	ext = filepath.Ext(base)
+	prefix = strings.TrimSuffix(base, ext) + "-"
+	return
+}
+
+// Sync commits the current contents of the file to stable storage.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file, and waits for any background compression and cleanup of
+// rotated files to complete. The Writer cannot be used after Close.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	close(w.millCh)
+	<-w.millDone
+	return err
+}
+
+// mill compresses and removes rotated files whenever signalled on millCh.
+func (w *Writer) mill() {
+	defer close(w.millDone)
+	for range w.millCh {
+		// Errors here have nowhere to go, since this is the log output itself - the
+		// next rotation will simply try again.
+		_ = w.millOnce()
+	}
+}
+
+func (w *Writer) millOnce() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	if w.opts.MaxBackups > 0 && len(backups) > w.opts.MaxBackups {
+		for _, b := range backups[w.opts.MaxBackups:] {
+			_ = os.Remove(b)
+		}
+		backups = backups[:w.opts.MaxBackups]
+	}
+
+	if w.opts.Compress {
+		for _, b := range backups {
+			if strings.HasSuffix(b, compressSuffix) {
+				continue
+			}
+			if err := compress(b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backups returns the paths of all rotated files, newest first.
+func (w *Writer) backups() ([]string, error) {
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type backup struct {
+		path string
+		t    time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), compressSuffix)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		t, err := time.Parse(backupTimeFormat, ts)
+		if err != nil {
+			continue // not one of ours
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, e.Name()), t: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+	paths := make([]string, len(backups))
+	for i, b := range backups {
+		paths[i] = b.path
+	}
+	return paths, nil
+}
+
+// compress gzips the file at path into path + compressSuffix, and removes the
+// original.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
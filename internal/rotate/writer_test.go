@@ -0,0 +1,163 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestWriter(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("size", func(t *testing.T) {
+		dir := t.TempDir()
+		w, err := open(filepath.Join(dir, "sub", "app.log"), Options{MaxSize: 10}, clock.now)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte("12345\n"))
+		require.NoError(t, err)
+		_, err = w.Write([]byte("67890\n")) // rotates
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, []string{"app-2022-01-01T00-00-00.000.log", "app.log"},
+			listDir(t, filepath.Join(dir, "sub")))
+		assert.Equal(t, "12345\n", readFile(t, filepath.Join(dir, "sub", "app-2022-01-01T00-00-00.000.log")))
+		assert.Equal(t, "67890\n", readFile(t, filepath.Join(dir, "sub", "app.log")))
+	})
+
+	t.Run("age", func(t *testing.T) {
+		dir := t.TempDir()
+		w, err := open(filepath.Join(dir, "app.log"), Options{MaxAge: time.Hour}, clock.now)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte("first\n"))
+		require.NoError(t, err)
+		clock.advance(30 * time.Minute)
+		_, err = w.Write([]byte("second\n"))
+		require.NoError(t, err)
+		clock.advance(30 * time.Minute)
+		_, err = w.Write([]byte("third\n")) // rotates
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, []string{"app-2022-01-01T01-00-00.000.log", "app.log"}, listDir(t, dir))
+		assert.Equal(t, "first\nsecond\n", readFile(t, filepath.Join(dir, "app-2022-01-01T01-00-00.000.log")))
+	})
+
+	t.Run("retention and compression", func(t *testing.T) {
+		dir := t.TempDir()
+		w, err := open(filepath.Join(dir, "app.log"), Options{
+			MaxSize:    1,
+			MaxBackups: 2,
+			Compress:   true,
+		}, clock.now)
+		require.NoError(t, err)
+
+		for _, msg := range []string{"a", "b", "c", "d"} {
+			clock.advance(time.Second)
+			_, err = w.Write([]byte(msg))
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+
+		// Only the latest 2 rotated files are retained
+		assert.Equal(t, []string{
+			"app-2022-01-01T01-00-03.000.log.gz",
+			"app-2022-01-01T01-00-04.000.log.gz",
+			"app.log",
+		}, listDir(t, dir))
+
+		f, err := os.Open(filepath.Join(dir, "app-2022-01-01T01-00-04.000.log.gz"))
+		require.NoError(t, err)
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		b, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		assert.Equal(t, "c", string(b))
+	})
+
+	t.Run("same millisecond", func(t *testing.T) {
+		dir := t.TempDir()
+		w, err := open(filepath.Join(dir, "app.log"), Options{MaxSize: 1}, clock.now)
+		require.NoError(t, err)
+
+		for _, msg := range []string{"a", "b", "c"} {
+			_, err = w.Write([]byte(msg))
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+
+		// Backups are not overwritten, and remain in order
+		assert.Equal(t, []string{
+			"app-2022-01-01T01-00-04.000.log",
+			"app-2022-01-01T01-00-04.001.log",
+			"app.log",
+		}, listDir(t, dir))
+		assert.Equal(t, "a", readFile(t, filepath.Join(dir, "app-2022-01-01T01-00-04.000.log")))
+		assert.Equal(t, "b", readFile(t, filepath.Join(dir, "app-2022-01-01T01-00-04.001.log")))
+		assert.Equal(t, "c", readFile(t, filepath.Join(dir, "app.log")))
+	})
+
+	t.Run("file removed", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		w, err := open(path, Options{MaxSize: 1}, clock.now)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte("a"))
+		require.NoError(t, err)
+		require.NoError(t, os.Remove(path))
+
+		// Rotation starts a new file instead of failing
+		_, err = w.Write([]byte("b"))
+		require.NoError(t, err)
+		_, err = w.Write([]byte("c"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, []string{"app-2022-01-01T01-00-04.000.log", "app.log"}, listDir(t, dir))
+		assert.Equal(t, "c", readFile(t, path))
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		w, err := open(filepath.Join(t.TempDir(), "app.log"), Options{}, clock.now)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.NoError(t, w.Close())
+
+		_, err = w.Write([]byte("foo"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	})
+}
@@ -0,0 +1,83 @@
+package scopematch
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Override adjusts the level for specific scopes.
+type Override struct {
+	// Scope matches the names of zapcore.Entry, including children of matching scopes.
+	// If multiple overrides match an entry, the most specific match is used.
+	Scope Pattern
+	// Level is the level to log at for zapcore.Entry's that match Scope.
+	Level zapcore.Level
+}
+
+// Match returns the override in overrides that most specifically matches scope.
+func Match(overrides []Override, scope string) (Override, bool) {
+	return Best(scope, overrides, func(o Override) Pattern { return o.Scope })
+}
+
+// Levels holds a root level and scope overrides that can be shared by cores and changed
+// at runtime with Set, which takes effect on all cores sharing the Levels immediately.
+type Levels struct {
+	state atomic.Pointer[levelsState]
+}
+
+type levelsState struct {
+	level     zapcore.Level
+	overrides []Override
+	// min is the lowest level enabled by level or any override.
+	min zapcore.Level
+}
+
+var _ zapcore.LevelEnabler = &Levels{}
+
+// NewLevels instantiates Levels with the given root level and scope overrides.
+func NewLevels(level zapcore.Level, overrides []Override) *Levels {
+	l := &Levels{}
+	l.Set(level, overrides)
+	return l
+}
+
+// Set replaces the root level and scope overrides.
+func (l *Levels) Set(level zapcore.Level, overrides []Override) {
+	min := level
+	for _, o := range overrides {
+		if o.Level < min {
+			min = o.Level
+		}
+	}
+	l.state.Store(&levelsState{
+		level:     level,
+		overrides: append([]Override(nil), overrides...),
+		min:       min,
+	})
+}
+
+// Get returns the current root level and scope overrides.
+func (l *Levels) Get() (zapcore.Level, []Override) {
+	s := l.state.Load()
+	return s.level, append([]Override(nil), s.overrides...)
+}
+
+// Level returns the lowest level enabled for any scope.
+func (l *Levels) Level() zapcore.Level {
+	return l.state.Load().min
+}
+
+// Enabled reports whether lvl is enabled for any scope.
+func (l *Levels) Enabled(lvl zapcore.Level) bool {
+	return l.state.Load().min.Enabled(lvl)
+}
+
+// EnabledFor reports whether lvl is enabled for the given scope.
+func (l *Levels) EnabledFor(scope string, lvl zapcore.Level) bool {
+	s := l.state.Load()
+	if o, ok := Match(s.overrides, scope); ok {
+		return o.Level.Enabled(lvl)
+	}
+	return s.level.Enabled(lvl)
+}
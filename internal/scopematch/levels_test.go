@@ -0,0 +1,38 @@
+package scopematch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevels(t *testing.T) {
+	l := NewLevels(zapcore.ErrorLevel, nil)
+	assert.Equal(t, zapcore.ErrorLevel, l.Level())
+	assert.False(t, l.Enabled(zapcore.WarnLevel))
+	assert.False(t, l.EnabledFor("foo", zapcore.WarnLevel))
+
+	l.Set(zapcore.ErrorLevel, []Override{
+		{Scope: MustParse("foo"), Level: zapcore.WarnLevel},
+		{Scope: MustParse("foo.*"), Level: zapcore.FatalLevel},
+	})
+	assert.Equal(t, zapcore.WarnLevel, l.Level())
+	assert.True(t, l.Enabled(zapcore.WarnLevel))
+	assert.True(t, l.EnabledFor("foo", zapcore.WarnLevel))
+	assert.False(t, l.EnabledFor("foo.bar", zapcore.ErrorLevel))
+	assert.False(t, l.EnabledFor("bar", zapcore.WarnLevel))
+	assert.True(t, l.EnabledFor("bar", zapcore.ErrorLevel))
+
+	level, overrides := l.Get()
+	assert.Equal(t, zapcore.ErrorLevel, level)
+	assert.Len(t, overrides, 2)
+
+	// A parent override takes precedence over "**"
+	l.Set(zapcore.ErrorLevel, []Override{
+		{Scope: MustParse("**"), Level: zapcore.WarnLevel},
+		{Scope: MustParse("gitserver"), Level: zapcore.DebugLevel},
+	})
+	assert.True(t, l.EnabledFor("gitserver.fetch", zapcore.DebugLevel))
+	assert.False(t, l.EnabledFor("frontend", zapcore.InfoLevel))
+}
@@ -0,0 +1,181 @@
+// Package scopematch implements matching of logger scopes against patterns, for
+// configuration that applies to specific scopes such as level overrides.
+package scopematch
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern matches the full names of scopes. A Pattern can be:
+//
+//   - A scope name, e.g. "foo.bar", which matches the scope and its children, e.g.
+//     "foo.bar" and "foo.bar.baz" but not "foo.barbaz".
+//   - A glob, where each segment between '.' is matched with path.Match, and "**"
+//     matches any number of segments. For example, "*.httpapi" matches "frontend.httpapi"
+//     and "**.httpapi" matches "a.b.httpapi". Like scope names, globs also match the
+//     children of matching scopes.
+//   - A regular expression enclosed in '/', e.g. "/foo\.(bar|baz)/", which must match
+//     the full scope name. Regular expressions are less specific than any scope name
+//     or glob, since how much of the scope they match cannot be determined.
+type Pattern struct {
+	raw string
+
+	// segments is the pattern split on '.', and literals the number of segments that
+	// do not contain wildcards. segments is nil for regular expressions.
+	segments []string
+	literals int
+
+	re *regexp.Regexp
+}
+
+// Parse parses a Pattern.
+func Parse(pattern string) (Pattern, error) {
+	if pattern == "" {
+		return Pattern{}, errors.New("empty scope pattern")
+	}
+
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile("^(?:" + pattern[1:len(pattern)-1] + ")$")
+		if err != nil {
+			return Pattern{}, fmt.Errorf("invalid scope pattern %q: %w", pattern, err)
+		}
+		return Pattern{raw: pattern, re: re}, nil
+	}
+
+	p := Pattern{raw: pattern, segments: strings.Split(pattern, ".")}
+	for _, s := range p.segments {
+		if s == "" {
+			return Pattern{}, fmt.Errorf("invalid scope pattern %q: empty segment", pattern)
+		}
+		if _, err := path.Match(s, ""); err != nil {
+			return Pattern{}, fmt.Errorf("invalid scope pattern %q: %w", pattern, err)
+		}
+		if !hasWildcard(s) {
+			p.literals++
+		}
+	}
+	return p, nil
+}
+
+// MustParse is like Parse, but panics if the pattern is invalid.
+func MustParse(pattern string) Pattern {
+	p, err := Parse(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the pattern as it was parsed.
+func (p Pattern) String() string { return p.raw }
+
+// Specificity describes how closely a Pattern matched a scope.
+type Specificity struct {
+	// depth is the number of segments of the scope that were matched, not counting
+	// segments matched by "**". Patterns that match a scope itself are more specific
+	// than patterns that match its parents.
+	depth int
+	// literals is the number of segments of the pattern without wildcards.
+	literals int
+	// regex is set if the pattern is a regular expression.
+	regex bool
+}
+
+// MoreSpecificThan reports whether s is a more specific match than o, i.e. whether s
+// matched more segments of the scope than o or, if they matched the same number of
+// segments, whether s matched more of them literally. Matches by regular expressions
+// are less specific than any other match.
+func (s Specificity) MoreSpecificThan(o Specificity) bool {
+	if s.regex != o.regex {
+		return o.regex
+	}
+	if s.depth != o.depth {
+		return s.depth > o.depth
+	}
+	return s.literals > o.literals
+}
+
+// Match reports whether the pattern matches scope, and how specific the match is.
+func (p Pattern) Match(scope string) (Specificity, bool) {
+	if p.re != nil {
+		if !p.re.MatchString(scope) {
+			return Specificity{}, false
+		}
+		return Specificity{regex: true}, true
+	}
+
+	if p.literals == len(p.segments) {
+		// Fast path for scope names
+		if !strings.HasPrefix(scope, p.raw) {
+			return Specificity{}, false
+		}
+		// Check that if p.raw != scope then it is a child scope
+		if len(scope) > len(p.raw) && scope[len(p.raw)] != '.' {
+			return Specificity{}, false
+		}
+		return Specificity{depth: len(p.segments), literals: p.literals}, true
+	}
+
+	depth, ok := matchSegments(p.segments, strings.Split(scope, "."))
+	if !ok {
+		return Specificity{}, false
+	}
+	return Specificity{depth: depth, literals: p.literals}, true
+}
+
+// matchSegments matches the pattern segments against a prefix of the scope segments,
+// returning the number of scope segments matched by segments other than "**". Segments
+// matched by "**" do not count, so that e.g. "**" is less specific than "foo" for
+// "foo.bar".
+func matchSegments(pattern, scope []string) (int, bool) {
+	if len(pattern) == 0 {
+		// Any remaining scope segments are children of a matched scope
+		return 0, true
+	}
+
+	if pattern[0] == "**" {
+		for i := len(scope); i >= 0; i-- {
+			if n, ok := matchSegments(pattern[1:], scope[i:]); ok {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+
+	if len(scope) == 0 {
+		return 0, false
+	}
+	if ok, _ := path.Match(pattern[0], scope[0]); !ok {
+		return 0, false
+	}
+	n, ok := matchSegments(pattern[1:], scope[1:])
+	return n + 1, ok
+}
+
+func hasWildcard(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
+
+// Best returns the item whose pattern most specifically matches scope. If multiple
+// items match equally specifically, the first one is returned.
+func Best[T any](scope string, items []T, patternOf func(T) Pattern) (T, bool) {
+	var (
+		best      T
+		bestMatch Specificity
+		found     bool
+	)
+	for _, item := range items {
+		s, ok := patternOf(item).Match(scope)
+		if !ok {
+			continue
+		}
+		if !found || s.MoreSpecificThan(bestMatch) {
+			best, bestMatch, found = item, s, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,105 @@
+package scopematch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, pattern := range []string{"", "foo..bar", ".foo", "foo.[", "/(/"} {
+		_, err := Parse(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{{
+		pattern: "foo.bar",
+		match:   []string{"foo.bar", "foo.bar.baz"},
+		noMatch: []string{"foo", "foo.barbaz", "foo.baz.bar"},
+	}, {
+		pattern: "*.httpapi",
+		match:   []string{"frontend.httpapi", "gitserver.httpapi.handler"},
+		noMatch: []string{"httpapi", "a.b.httpapi", "frontend.httpapi2"},
+	}, {
+		pattern: "gitserver.*.fetch",
+		match:   []string{"gitserver.repos.fetch", "gitserver.repos.fetch.git"},
+		noMatch: []string{"gitserver.fetch", "gitserver.a.b.fetch"},
+	}, {
+		pattern: "**.httpapi",
+		match:   []string{"httpapi", "a.httpapi", "a.b.httpapi.c"},
+		noMatch: []string{"a.b"},
+	}, {
+		pattern: "search.zoekt*",
+		match:   []string{"search.zoekt", "search.zoektweb.foo"},
+		noMatch: []string{"search.zoek"},
+	}, {
+		pattern: `/foo\.(bar|baz)/`,
+		match:   []string{"foo.bar", "foo.baz"},
+		noMatch: []string{"foo.bar.child", "xfoo.bar"},
+	}} {
+		t.Run(tc.pattern, func(t *testing.T) {
+			p, err := Parse(tc.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tc.pattern, p.String())
+			for _, scope := range tc.match {
+				_, ok := p.Match(scope)
+				assert.True(t, ok, scope)
+			}
+			for _, scope := range tc.noMatch {
+				_, ok := p.Match(scope)
+				assert.False(t, ok, scope)
+			}
+		})
+	}
+}
+
+func TestBest(t *testing.T) {
+	patterns := []Pattern{
+		MustParse("a"),
+		MustParse("a.b"),
+		MustParse("*.b"),
+		MustParse("a.*.c"),
+		MustParse("**.c"),
+		MustParse("/z\\.x\\..*/"),
+	}
+	best := func(scope string) string {
+		p, ok := Best(scope, patterns, func(p Pattern) Pattern { return p })
+		if !ok {
+			return ""
+		}
+		return p.String()
+	}
+
+	assert.Equal(t, "a", best("a"))
+	// Literal match beats glob at the same depth
+	assert.Equal(t, "a.b", best("a.b"))
+	assert.Equal(t, "*.b", best("z.b"))
+	// Deeper match beats shallower literal match
+	assert.Equal(t, "a.*.c", best("a.b.c"))
+	assert.Equal(t, "a.*.c", best("a.b.c.d"))
+	assert.Equal(t, "**.c", best("z.y.c"))
+	assert.Equal(t, "/z\\.x\\..*/", best("z.x.y"))
+	assert.Equal(t, "", best("z"))
+
+	// Segments matched by "**" do not count towards specificity
+	patterns = []Pattern{MustParse("**"), MustParse("gitserver")}
+	assert.Equal(t, "gitserver", best("gitserver.fetch"))
+	assert.Equal(t, "**", best("frontend"))
+	patterns = []Pattern{MustParse("**.fetch"), MustParse("gitserver.fetch")}
+	assert.Equal(t, "gitserver.fetch", best("gitserver.fetch"))
+	assert.Equal(t, "**.fetch", best("frontend.fetch"))
+
+	// Regular expressions are less specific than any scope name or glob
+	patterns = []Pattern{MustParse("/.*/"), MustParse("foo.bar"), MustParse("**")}
+	assert.Equal(t, "foo.bar", best("foo.bar.baz"))
+	assert.Equal(t, "**", best("other"))
+	patterns = []Pattern{MustParse("/.*/"), MustParse("/foo\\..*/")}
+	assert.Equal(t, "/.*/", best("foo.bar"))
+}
@@ -0,0 +1,104 @@
+// Package attributes provides helpers for cores that handle the Resource the root logger
+// is annotated with separately from other fields, and that render fields as flat
+// key-value attributes.
+package attributes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+// Set holds the fields accumulated on a core, with the Resource extracted from them.
+type Set struct {
+	// Resource is the Resource the root logger is annotated with, if any.
+	Resource *encoders.ResourceEncoder
+	// Fields are the fields accumulated so far, excluding the Resource and the namespace
+	// that attributes are nested in for output.
+	Fields []zapcore.Field
+}
+
+// With returns a copy of s with fields added, for use in zapcore.Core.With and Write.
+// s itself is not modified, so that it can be shared by clones of a core.
+func (s Set) With(fields []zapcore.Field) Set {
+	clone := Set{
+		Resource: s.Resource,
+		Fields:   make([]zapcore.Field, len(s.Fields), len(s.Fields)+len(fields)),
+	}
+	copy(clone.Fields, s.Fields)
+	for _, f := range fields {
+		if r, ok := f.Interface.(*encoders.ResourceEncoder); ok && f.Key == otelfields.ResourceFieldKey {
+			clone.Resource = r
+			continue
+		}
+		if f.Equals(otelfields.AttributesNamespace) {
+			// Fields are all attributes already.
+			continue
+		}
+		clone.Fields = append(clone.Fields, f)
+	}
+	return clone
+}
+
+// Encode renders the fields in s with zapcore.MapObjectEncoder.
+func (s Set) Encode() map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range s.Fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+// Flatten calls fn with each value of fields rendered by zapcore.MapObjectEncoder in
+// order of their keys, joining the keys of nested objects with sep.
+func Flatten(fields map[string]interface{}, sep string, fn func(key string, value interface{})) {
+	flatten("", fields, sep, fn)
+}
+
+func flatten(prefix string, fields map[string]interface{}, sep string, fn func(key string, value interface{})) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + sep + k
+		}
+		if m, ok := fields[k].(map[string]interface{}); ok {
+			flatten(key, m, sep, fn)
+		} else {
+			fn(key, fields[k])
+		}
+	}
+}
+
+// String renders a value rendered by zapcore.MapObjectEncoder as a string. Values
+// without a natural string representation, such as arrays, are rendered as JSON.
+func String(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64:
+		return fmt.Sprint(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return string(b)
+}
@@ -0,0 +1,50 @@
+package attributes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+func TestSet(t *testing.T) {
+	resource := &encoders.ResourceEncoder{Resource: otelfields.Resource{Name: "foo"}}
+	parent := Set{}.With([]zapcore.Field{
+		zap.Object(otelfields.ResourceFieldKey, resource),
+		otelfields.AttributesNamespace,
+		zap.String("a", "1"),
+	})
+	child := parent.With([]zapcore.Field{zap.String("b", "2")})
+	sibling := parent.With([]zapcore.Field{zap.String("c", "3")})
+
+	assert.Same(t, resource, child.Resource)
+	assert.Equal(t, map[string]interface{}{"a": "1"}, parent.Encode())
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, child.Encode())
+	assert.Equal(t, map[string]interface{}{"a": "1", "c": "3"}, sibling.Encode())
+}
+
+func TestFlatten(t *testing.T) {
+	var keys []string
+	var values []string
+	Flatten(map[string]interface{}{
+		"b":  "v",
+		"a":  int64(1),
+		"ns": map[string]interface{}{"c": true, "arr": []interface{}{"x", "y"}},
+	}, ".", func(key string, value interface{}) {
+		keys = append(keys, key)
+		values = append(values, String(value))
+	})
+	assert.Equal(t, []string{"a", "b", "ns.arr", "ns.c"}, keys)
+	assert.Equal(t, []string{"1", "v", `["x","y"]`, "true"}, values)
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "2023-01-02T03:04:05Z", String(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.Equal(t, "1s", String(time.Second))
+	assert.Equal(t, "bytes", String([]byte("bytes")))
+}
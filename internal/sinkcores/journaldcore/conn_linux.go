@@ -0,0 +1,75 @@
+package journaldcore
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Conn is a connection to the journald native socket.
+type Conn struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+// Dial opens a connection to the journald native socket at path.
+func Dial(path string) (*Conn, error) {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	// Check that journald is listening, so that misconfiguration is surfaced early.
+	if _, err := os.Stat(path); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &Conn{
+		conn: conn,
+		addr: &net.UnixAddr{Name: path, Net: "unixgram"},
+	}, nil
+}
+
+func (c *Conn) send(entry []byte) error {
+	_, _, err := c.conn.WriteMsgUnix(entry, nil, c.addr)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+
+	// The entry is too large for a datagram, so write it to a temporary file and send
+	// the file descriptor instead.
+	f, err := tempFile()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(entry); err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	_, _, err = c.conn.WriteMsgUnix(nil, rights, c.addr)
+	return err
+}
+
+// tempFile creates an unlinked temporary file, preferring /dev/shm so that it is
+// backed by memory.
+func tempFile() (*os.File, error) {
+	f, err := os.CreateTemp("/dev/shm", "journal.")
+	if err != nil {
+		f, err = os.CreateTemp("", "journal.")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error { return c.conn.Close() }
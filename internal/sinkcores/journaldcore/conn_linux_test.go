@@ -0,0 +1,63 @@
+package journaldcore
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = server.Close() })
+
+	conn, err := Dial(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	t.Run("datagram", func(t *testing.T) {
+		require.NoError(t, conn.send([]byte("MESSAGE=hello\n")))
+
+		buf := make([]byte, 1024)
+		n, _, _, _, err := server.ReadMsgUnix(buf, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "MESSAGE=hello\n", string(buf[:n]))
+	})
+
+	t.Run("file descriptor", func(t *testing.T) {
+		// Larger than the maximum datagram size
+		entry := append([]byte("MESSAGE="), bytes.Repeat([]byte("a"), 4<<20)...)
+		require.NoError(t, conn.send(entry))
+
+		oob := make([]byte, syscall.CmsgSpace(4))
+		_, oobn, _, _, err := server.ReadMsgUnix(nil, oob)
+		require.NoError(t, err)
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		require.NoError(t, err)
+		require.Len(t, fds, 1)
+
+		f := os.NewFile(uintptr(fds[0]), "journal")
+		defer f.Close()
+		_, err = f.Seek(0, 0)
+		require.NoError(t, err)
+		got, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, entry, got)
+	})
+}
+
+func TestDialMissingSocket(t *testing.T) {
+	_, err := Dial(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
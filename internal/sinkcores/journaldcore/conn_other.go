@@ -0,0 +1,20 @@
+//go:build !linux
+
+package journaldcore
+
+import "errors"
+
+// Conn is a connection to the journald native socket.
+type Conn struct{}
+
+// Dial always fails, since journald is only available on Linux.
+func Dial(path string) (*Conn, error) {
+	return nil, errors.New("journald is only supported on Linux")
+}
+
+func (c *Conn) send(entry []byte) error {
+	return errors.New("journald is only supported on Linux")
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error { return nil }
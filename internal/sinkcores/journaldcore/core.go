@@ -0,0 +1,98 @@
+package journaldcore
+
+import (
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/sinkcores/attributes"
+	"github.com/sourcegraph/log/internal/sinkcores/syslogcore"
+)
+
+// sender sends a serialized journal entry.
+type sender interface {
+	send(entry []byte) error
+}
+
+// core serializes entries in the native journal protocol and sends them to journald.
+//
+// The message of each entry is sent as MESSAGE, its level as PRIORITY, and its scope as
+// SYSLOG_IDENTIFIER (or the Resource name, for entries without a scope). The caller is
+// sent as CODE_FILE, CODE_LINE and CODE_FUNC, and the Resource and all attributes are
+// sent as uppercase journal fields.
+type core struct {
+	zapcore.LevelEnabler
+
+	sender sender
+
+	// attrs are the attributes accumulated on this core.
+	attrs attributes.Set
+}
+
+var _ zapcore.Core = &core{}
+
+// NewCore instantiates a core that writes entries enabled by level to conn.
+func NewCore(level zapcore.LevelEnabler, conn *Conn) zapcore.Core {
+	return newCore(level, conn)
+}
+
+func newCore(level zapcore.LevelEnabler, s sender) *core {
+	return &core{
+		LevelEnabler: level,
+		sender:       s,
+	}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.attrs = c.attrs.With(fields)
+	return &clone
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	attrs := c.attrs.With(fields)
+
+	buf := appendField(nil, "MESSAGE", ent.Message)
+	buf = appendField(buf, "PRIORITY", strconv.Itoa(syslogcore.Severity(ent.Level)))
+
+	identifier := ent.LoggerName
+	if identifier == "" && attrs.Resource != nil {
+		identifier = attrs.Resource.Name
+	}
+	if identifier != "" {
+		buf = appendField(buf, "SYSLOG_IDENTIFIER", identifier)
+	}
+
+	// https://www.freedesktop.org/software/systemd/man/systemd.journal-fields.html
+	if ent.Caller.Defined {
+		buf = appendField(buf, "CODE_FILE", ent.Caller.File)
+		buf = appendField(buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			buf = appendField(buf, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		buf = appendField(buf, "STACKTRACE", ent.Stack)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	if attrs.Resource != nil {
+		_ = attrs.Resource.MarshalLogObject(enc)
+	}
+	for _, f := range attrs.Fields {
+		f.AddTo(enc)
+	}
+	buf = appendAttributes(buf, enc.Fields)
+
+	return c.sender.send(buf)
+}
+
+// Sync is a no-op, since entries are sent as they are logged.
+func (c *core) Sync() error { return nil }
@@ -0,0 +1,93 @@
+package journaldcore
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+type testSender struct{ entries []string }
+
+func (s *testSender) send(entry []byte) error {
+	s.entries = append(s.entries, string(entry))
+	return nil
+}
+
+func TestAppendField(t *testing.T) {
+	assert.Equal(t, "FOO=bar\n", string(appendField(nil, "FOO", "bar")))
+
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, 7)
+	assert.Equal(t, "FOO\n"+string(size)+"bar\nbaz\n", string(appendField(nil, "FOO", "bar\nbaz")))
+}
+
+func TestFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"foo":              "FOO",
+		"foo.bar-baz":      "FOO_BAR_BAZ",
+		"_private":         "PRIVATE",
+		"123abc":           "ABC",
+		"...":              "",
+		"TraceId":          "TRACEID",
+		"service.instance": "SERVICE_INSTANCE",
+	} {
+		assert.Equal(t, want, fieldName(key), key)
+	}
+	assert.Len(t, fieldName(string(make([]byte, 100))+"a"), 1)
+}
+
+func TestCore(t *testing.T) {
+	s := &testSender{}
+	logger := zap.New(newCore(zapcore.InfoLevel, s)).With(
+		zap.Object(otelfields.ResourceFieldKey, &encoders.ResourceEncoder{
+			Resource: otelfields.Resource{Name: "my-service"},
+		}),
+		otelfields.AttributesNamespace)
+
+	logger.Info("no scope")
+	logger.Named("foo").Debug("dropped")
+	logger.Named("foo").Warn("multi\nline",
+		zap.String("key", "value"),
+		zap.Namespace("ns"),
+		zap.Error(errors.New("oh\nno")))
+
+	require.Len(t, s.entries, 2)
+	assert.Equal(t, "MESSAGE=no scope\nPRIORITY=6\nSYSLOG_IDENTIFIER=my-service\nSERVICE_NAME=my-service\n", s.entries[0])
+
+	var buf []byte
+	buf = appendField(buf, "MESSAGE", "multi\nline")
+	buf = appendField(buf, "PRIORITY", "4")
+	buf = appendField(buf, "SYSLOG_IDENTIFIER", "foo")
+	buf = appendField(buf, "KEY", "value")
+	buf = appendField(buf, "NS_ERROR", "oh\nno")
+	buf = appendField(buf, "SERVICE_NAME", "my-service")
+	assert.Equal(t, string(buf), s.entries[1])
+
+	t.Run("reserved fields", func(t *testing.T) {
+		s := &testSender{}
+		zap.New(newCore(zapcore.InfoLevel, s)).Named("foo").Info("hello",
+			zap.String("message", "spoofed"),
+			zap.Int("priority", 0),
+			zap.String("syslog.identifier", "other"),
+			zap.String("_pid", "1"))
+
+		require.Len(t, s.entries, 1)
+		var buf []byte
+		buf = appendField(buf, "MESSAGE", "hello")
+		buf = appendField(buf, "PRIORITY", "6")
+		buf = appendField(buf, "SYSLOG_IDENTIFIER", "foo")
+		buf = appendField(buf, "PID", "1")
+		buf = appendField(buf, "ATTR_MESSAGE", "spoofed")
+		buf = appendField(buf, "ATTR_PRIORITY", "0")
+		buf = appendField(buf, "ATTR_SYSLOG_IDENTIFIER", "other")
+		assert.Equal(t, string(buf), s.entries[0])
+	})
+}
@@ -0,0 +1,7 @@
+// journaldcore provides a sink that writes entries to systemd-journald with the native
+// journal protocol, so that attributes can be filtered on with journalctl.
+//
+// https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+//
+// Writing to journald is only supported on Linux.
+package journaldcore
@@ -0,0 +1,103 @@
+package journaldcore
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/sourcegraph/log/internal/sinkcores/attributes"
+)
+
+// maxFieldName is the maximum length of a journal field name.
+const maxFieldName = 64
+
+// reservedPrefix is prepended to the names of attributes that would otherwise clash
+// with reservedFields.
+const reservedPrefix = "ATTR_"
+
+// reservedFields are the user journal fields with special meaning, which are set by the
+// core or interpreted by journald. Trusted fields, which start with '_', can never be
+// set by attributes since fieldName drops leading underscores.
+//
+// https://www.freedesktop.org/software/systemd/man/systemd.journal-fields.html
+var reservedFields = map[string]struct{}{
+	"MESSAGE":            {},
+	"MESSAGE_ID":         {},
+	"PRIORITY":           {},
+	"CODE_FILE":          {},
+	"CODE_LINE":          {},
+	"CODE_FUNC":          {},
+	"ERRNO":              {},
+	"INVOCATION_ID":      {},
+	"USER_INVOCATION_ID": {},
+	"SYSLOG_FACILITY":    {},
+	"SYSLOG_IDENTIFIER":  {},
+	"SYSLOG_PID":         {},
+	"SYSLOG_TIMESTAMP":   {},
+	"SYSLOG_RAW":         {},
+	"DOCUMENTATION":      {},
+	"TID":                {},
+	"UNIT":               {},
+	"USER_UNIT":          {},
+	"STACKTRACE":         {},
+}
+
+// appendField appends a field in the native journal protocol format to buf. Values
+// containing newlines are encoded in the binary-safe format.
+func appendField(buf []byte, name, value string) []byte {
+	if !strings.ContainsRune(value, '\n') {
+		buf = append(buf, name...)
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+
+	buf = append(buf, name...)
+	buf = append(buf, '\n')
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+// fieldName converts key into a valid journal field name, which may only consist of
+// uppercase letters, digits and underscores, and must not start with a digit or an
+// underscore. Invalid characters are replaced with '_', and leading digits and
+// underscores are dropped. An empty string is returned if no valid name remains.
+func fieldName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(name) < maxFieldName; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9', c == '_':
+			if len(name) == 0 {
+				continue
+			}
+		default:
+			if len(name) == 0 {
+				continue
+			}
+			c = '_'
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
+// appendAttributes flattens fields rendered by zapcore.MapObjectEncoder into journal
+// fields, joining the keys of nested objects with '_'. Attributes whose names clash with
+// reservedFields are prefixed with reservedPrefix.
+func appendAttributes(buf []byte, fields map[string]interface{}) []byte {
+	attributes.Flatten(fields, "_", func(key string, value interface{}) {
+		name := fieldName(key)
+		if name == "" {
+			return
+		}
+		if _, ok := reservedFields[name]; ok {
+			name = reservedPrefix + name
+		}
+		buf = appendField(buf, name, attributes.String(value))
+	})
+	return buf
+}
@@ -0,0 +1,145 @@
+// Package memorycore provides a core that keeps recent entries in a bounded in-memory
+// buffer, so that they can be queried and exported later.
+package memorycore
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a structured entry kept in a Buffer.
+type Entry struct {
+	Time    time.Time
+	Level   zapcore.Level
+	Scope   string
+	Message string
+	// Caller is the location of the log call, if available.
+	Caller string
+	// Fields holds the fields of the entry as encoded by zapcore.MapObjectEncoder,
+	// including fields accumulated on the logger.
+	Fields map[string]interface{}
+
+	// size is the approximate size of the entry in bytes.
+	size int
+}
+
+// Buffer is a ring buffer of entries bounded by both the number of entries and their
+// approximate total size. The oldest entries are discarded to make room for new ones.
+type Buffer struct {
+	maxEntries int
+	maxBytes   int
+
+	mu sync.Mutex
+	// entries holds the buffered entries from oldest to newest, starting at start.
+	entries []Entry
+	start   int
+	count   int
+	bytes   int
+}
+
+// NewBuffer instantiates a Buffer that holds at most maxEntries entries, and entries with
+// a total size of at most maxBytes bytes if maxBytes is positive.
+func NewBuffer(maxEntries, maxBytes int) *Buffer {
+	return &Buffer{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make([]Entry, maxEntries),
+	}
+}
+
+// Add adds e to the buffer, discarding the oldest entries if needed. Entries larger than
+// the buffer are discarded.
+func (b *Buffer) Add(e Entry) {
+	e.size = entrySize(e)
+	if b.maxEntries <= 0 || (b.maxBytes > 0 && e.size > b.maxBytes) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.count > 0 && (b.count == b.maxEntries || (b.maxBytes > 0 && b.bytes+e.size > b.maxBytes)) {
+		b.removeOldest()
+	}
+	b.entries[(b.start+b.count)%b.maxEntries] = e
+	b.count++
+	b.bytes += e.size
+}
+
+// removeOldest removes the oldest entry. b.mu must be held.
+func (b *Buffer) removeOldest() {
+	b.bytes -= b.entries[b.start].size
+	b.entries[b.start] = Entry{}
+	b.start = (b.start + 1) % b.maxEntries
+	b.count--
+}
+
+// Entries returns the buffered entries that match, from oldest to newest. If match is
+// nil, all entries are returned.
+func (b *Buffer) Entries(match func(*Entry) bool) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []Entry
+	for i := 0; i < b.count; i++ {
+		e := &b.entries[(b.start+i)%b.maxEntries]
+		if match == nil || match(e) {
+			entries = append(entries, *e)
+		}
+	}
+	return entries
+}
+
+// entrySize returns the approximate size of e in bytes, as encoded in JSON.
+func entrySize(e Entry) int {
+	size := len(e.Scope) + len(e.Message) + len(e.Caller) + 64 // time, level and overhead
+	if len(e.Fields) > 0 {
+		size += valueSize(e.Fields)
+	}
+	return size
+}
+
+// valueSize returns the approximate size of v in bytes, as encoded in JSON. Values of the
+// types produced by zapcore.MapObjectEncoder are estimated without encoding them, since
+// this is done for every entry.
+func valueSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 4
+	case string:
+		return len(v) + 2
+	case []byte:
+		return (len(v)+2)/3*4 + 2 // base64
+	case bool:
+		return 5
+	case time.Time:
+		return 37 // RFC 3339 with nanoseconds
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64, complex64, complex128, time.Duration:
+		return 8
+	case map[string]interface{}:
+		size := 2
+		for k, v := range v {
+			size += len(k) + 4 + valueSize(v)
+		}
+		return size
+	case []interface{}:
+		size := 2
+		for _, v := range v {
+			size += 1 + valueSize(v)
+		}
+		return size
+	}
+
+	// Reflected values are only known by encoding them
+	b, err := json.Marshal(v)
+	if err != nil {
+		// Values that cannot be encoded in JSON are only counted with the entry
+		// overhead
+		return 0
+	}
+	return len(b)
+}
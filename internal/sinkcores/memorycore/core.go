@@ -0,0 +1,56 @@
+package memorycore
+
+import (
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/sinkcores/attributes"
+)
+
+// Core adds entries to a Buffer.
+type Core struct {
+	zapcore.LevelEnabler
+
+	buffer *Buffer
+	// attrs are the attributes accumulated with With.
+	attrs attributes.Set
+}
+
+var _ zapcore.Core = &Core{}
+
+// NewCore instantiates a Core that adds entries enabled by level to buffer. The Resource
+// field is not kept, since it is the same for all entries.
+func NewCore(level zapcore.LevelEnabler, buffer *Buffer) *Core {
+	return &Core{LevelEnabler: level, buffer: buffer}
+}
+
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.attrs = c.attrs.With(fields)
+	return &clone
+}
+
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// Fields are kept flat, without the attributes namespace, so that they can be
+	// queried by key.
+	e := Entry{
+		Time:    ent.Time,
+		Level:   ent.Level,
+		Scope:   ent.LoggerName,
+		Message: ent.Message,
+		Fields:  c.attrs.With(fields).Encode(),
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	c.buffer.Add(e)
+	return nil
+}
+
+func (c *Core) Sync() error { return nil }
@@ -0,0 +1,97 @@
+package memorycore
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+func messages(entries []Entry) []string {
+	msgs := make([]string, len(entries))
+	for i, e := range entries {
+		msgs[i] = e.Message
+	}
+	return msgs
+}
+
+func TestBuffer(t *testing.T) {
+	t.Run("bounded by entries", func(t *testing.T) {
+		b := NewBuffer(2, 0)
+		for _, msg := range []string{"a", "b", "c"} {
+			b.Add(Entry{Message: msg})
+		}
+		assert.Equal(t, []string{"b", "c"}, messages(b.Entries(nil)))
+	})
+
+	t.Run("bounded by bytes", func(t *testing.T) {
+		b := NewBuffer(10, 250)
+		for _, msg := range []string{"a", "b", "c"} {
+			b.Add(Entry{Message: strings.Repeat(msg, 100)})
+		}
+		entries := b.Entries(nil)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, strings.Repeat("c", 100), entries[0].Message)
+
+		// Entries larger than the buffer are discarded
+		b.Add(Entry{Message: strings.Repeat("d", 300)})
+		assert.Len(t, b.Entries(nil), 1)
+	})
+
+	t.Run("match", func(t *testing.T) {
+		b := NewBuffer(10, 0)
+		for _, msg := range []string{"a", "b", "c"} {
+			b.Add(Entry{Message: msg})
+		}
+		assert.Equal(t, []string{"a", "c"}, messages(b.Entries(func(e *Entry) bool { return e.Message != "b" })))
+	})
+}
+
+func TestEntrySize(t *testing.T) {
+	fields := map[string]interface{}{
+		"str":  strings.Repeat("a", 100),
+		"num":  int64(1),
+		"obj":  map[string]interface{}{"nested": strings.Repeat("b", 100)},
+		"arr":  []interface{}{strings.Repeat("c", 100), true},
+		"refl": struct{ Foo string }{Foo: strings.Repeat("d", 100)},
+		"chan": make(chan int),
+	}
+	base := entrySize(Entry{})
+	size := entrySize(Entry{Fields: fields}) - base
+
+	// Close to the size encoded in JSON, except for values that cannot be encoded
+	delete(fields, "chan")
+	encoded, err := json.Marshal(fields)
+	require.NoError(t, err)
+	assert.InDelta(t, len(encoded), size, 32)
+}
+
+func TestCore(t *testing.T) {
+	b := NewBuffer(10, 0)
+	core := NewCore(zapcore.InfoLevel, b).
+		With([]zapcore.Field{
+			zap.Object(otelfields.ResourceFieldKey, &encoders.ResourceEncoder{Resource: otelfields.Resource{Name: "resource"}}),
+			zap.String("foo", "bar"),
+		}).
+		With([]zapcore.Field{otelfields.AttributesNamespace})
+
+	for _, level := range []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel} {
+		if ce := core.Check(zapcore.Entry{LoggerName: "scope", Level: level, Message: "hello"}, nil); ce != nil {
+			ce.Write(zap.Int("n", 1))
+		}
+	}
+
+	entries := b.Entries(nil)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "scope", entries[0].Scope)
+		assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+		assert.Equal(t, map[string]interface{}{"foo": "bar", "n": int64(1)}, entries[0].Fields)
+	}
+}
@@ -1,12 +1,11 @@
 package outputcore
 
 import (
-	"time"
-
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/scopematch"
 	"github.com/sourcegraph/log/output"
 )
 
@@ -29,7 +28,69 @@ func NewCore(
 	core := newOverrideCore(level, overrides, newCore)
 
 	if sampling.Initial > 0 {
-		return zapcore.NewSamplerWithOptions(core, time.Second, sampling.Initial, sampling.Thereafter)
+		return newSamplerCore(core, NewSampling(sampling, nil), NewDrops(DefaultDropReportInterval))
 	}
 	return core
 }
+
+// NewDynamicCore is like NewCore, but the root level and scope overrides are read from
+// levels, and the sampling configuration from sampling, on each entry - so that they can
+// be changed at runtime. Entries dropped by sampling are recorded in drops, and entries
+// that are not dropped are deduplicated if configured with dedup.
+func NewDynamicCore(
+	output zapcore.WriteSyncer,
+	levels *scopematch.Levels,
+	sampling *Sampling,
+	drops *Drops,
+	dedup DedupOptions,
+	format output.Format,
+	development bool,
+) zapcore.Core {
+	var base zapcore.Core = zapcore.NewCore(
+		encoders.BuildEncoder(format, development),
+		output,
+		levels,
+	)
+	if dedup.Window > 0 {
+		base = newDedupCore(base, dedup)
+	}
+	core := &dynamicCore{
+		Core:   base,
+		levels: levels,
+	}
+	return newSamplerCore(core, sampling, drops)
+}
+
+// dynamicCore wraps a core to only log entries enabled by levels for the scope of each
+// entry. The wrapped core must be built with levels as its level enabler, so that it
+// enables entries for any scope.
+type dynamicCore struct {
+	zapcore.Core
+
+	levels *scopematch.Levels
+}
+
+// Level returns the lowest level enabled for any scope, since child cores will shortcut
+// based on this level.
+func (c *dynamicCore) Level() zapcore.Level {
+	return c.levels.Level()
+}
+
+func (c *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dynamicCore{
+		Core:   c.Core.With(fields),
+		levels: c.levels,
+	}
+}
+
+// EnabledFor reports whether lvl is enabled for the given scope.
+func (c *dynamicCore) EnabledFor(scope string, lvl zapcore.Level) bool {
+	return c.levels.EnabledFor(scope, lvl)
+}
+
+func (c *dynamicCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.levels.EnabledFor(ent.LoggerName, ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
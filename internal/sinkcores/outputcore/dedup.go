@@ -0,0 +1,193 @@
+package outputcore
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxDedupEntries is the maximum number of distinct entries that are tracked for
+// deduplication. Further entries are written as-is until tracked entries expire.
+const maxDedupEntries = 1000
+
+// DedupOptions configures the collapsing of duplicate entries, i.e. entries with the
+// same scope, level, message and fields. The zero value disables deduplication.
+type DedupOptions struct {
+	// Window is the duration after an entry is written during which duplicates of the
+	// entry are collapsed.
+	Window time.Duration
+	// Consecutive only collapses duplicates that are not interleaved with other
+	// entries. Window still limits how long duplicates are collapsed for.
+	Consecutive bool
+}
+
+// dedupCore wraps a core to collapse duplicate entries. The first entry is written
+// as-is, and any duplicates are written as a single entry with the fields "repeated",
+// "firstSeen" and "lastSeen" once the window expires or a different entry is written in
+// consecutive mode. Expired windows are only checked on Write, so duplicates are also
+// written on Sync.
+type dedupCore struct {
+	zapcore.Core
+
+	state *dedupState
+	// context identifies the fields accumulated with With.
+	context string
+}
+
+type dedupState struct {
+	opts DedupOptions
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+	// last is the key of the last entry written, for consecutive mode.
+	last string
+	// nextSweep is when expired entries should next be removed.
+	nextSweep time.Time
+}
+
+type dedupEntry struct {
+	// core is the core the entry was written to, with its context.
+	core zapcore.Core
+	// expires is when the window of the entry ends.
+	expires time.Time
+
+	// ent and fields are those of the last duplicate.
+	ent    zapcore.Entry
+	fields []zapcore.Field
+	// repeated is the number of duplicates since the entry was written.
+	repeated            int
+	firstSeen, lastSeen time.Time
+}
+
+func newDedupCore(core zapcore.Core, opts DedupOptions) *dedupCore {
+	return &dedupCore{
+		Core: core,
+		state: &dedupState{
+			opts:    opts,
+			entries: make(map[string]*dedupEntry),
+		},
+	}
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{
+		Core:    c.Core.With(fields),
+		state:   c.state,
+		context: c.context + encodeFields(fields),
+	}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level > zapcore.ErrorLevel {
+		// Never hold back entries that panic or exit
+		return c.Core.Write(ent, fields)
+	}
+
+	key := strings.Join([]string{
+		ent.LoggerName, ent.Level.String(), ent.Message, c.context, encodeFields(fields),
+	}, "\x00")
+
+	s := c.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(ent.Time)
+
+	e, ok := s.entries[key]
+	if ok && ent.Time.Before(e.expires) && (!s.opts.Consecutive || s.last == key) {
+		if e.repeated == 0 {
+			e.firstSeen = ent.Time
+		}
+		e.repeated++
+		e.lastSeen = ent.Time
+		e.ent, e.fields = ent, fields
+		return nil
+	}
+
+	if s.opts.Consecutive {
+		// Only the last entry is tracked
+		s.flushAll()
+	} else if ok {
+		s.flush(key, e)
+	}
+
+	if len(s.entries) < maxDedupEntries {
+		s.entries[key] = &dedupEntry{
+			core:    c.Core,
+			expires: ent.Time.Add(s.opts.Window),
+		}
+	}
+	s.last = key
+	return c.Core.Write(ent, fields)
+}
+
+// Sync writes any collapsed duplicates before syncing the wrapped core.
+func (c *dedupCore) Sync() error {
+	c.state.mu.Lock()
+	c.state.flushAll()
+	c.state.mu.Unlock()
+	return c.Core.Sync()
+}
+
+// sweep writes collapsed duplicates of entries with expired windows and removes them,
+// at most once per window. s.mu must be held.
+func (s *dedupState) sweep(t time.Time) {
+	if t.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = t.Add(s.opts.Window)
+	for key, e := range s.entries {
+		if !t.Before(e.expires) {
+			s.flush(key, e)
+		}
+	}
+}
+
+// flushAll writes collapsed duplicates of all entries and removes them. s.mu must be
+// held.
+func (s *dedupState) flushAll() {
+	for key, e := range s.entries {
+		s.flush(key, e)
+	}
+}
+
+// flush writes collapsed duplicates of the entry, if any, and removes it. s.mu must be
+// held.
+func (s *dedupState) flush(key string, e *dedupEntry) {
+	delete(s.entries, key)
+	if e.repeated == 0 {
+		return
+	}
+	fields := make([]zapcore.Field, len(e.fields), len(e.fields)+3)
+	copy(fields, e.fields)
+	fields = append(fields,
+		zap.Int("repeated", e.repeated),
+		zap.Time("firstSeen", e.firstSeen),
+		zap.Time("lastSeen", e.lastSeen))
+	// Errors have nowhere to go, since this is the log output itself
+	_ = e.core.Write(e.ent, fields)
+}
+
+// encodeFields returns a string that identifies the values of fields.
+func encodeFields(fields []zapcore.Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	// fmt prints maps with sorted keys
+	return fmt.Sprint(enc.Fields)
+}
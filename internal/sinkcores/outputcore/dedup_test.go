@@ -0,0 +1,99 @@
+package outputcore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDedupCore(t *testing.T) {
+	start := time.Now().Round(0) // strip the monotonic clock reading
+	write := func(core zapcore.Core, offset time.Duration, msg string, fields ...zapcore.Field) {
+		ent := zapcore.Entry{LoggerName: "scope", Level: zapcore.InfoLevel, Message: msg, Time: start.Add(offset)}
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write(fields...)
+		}
+	}
+
+	t.Run("windowed", func(t *testing.T) {
+		observed, logs := observer.New(zapcore.DebugLevel)
+		core := newDedupCore(observed, DedupOptions{Window: time.Minute})
+
+		write(core, 0, "retrying", zap.Int("attempt", 1))
+		write(core, 1*time.Second, "retrying", zap.Int("attempt", 1))
+		write(core, 2*time.Second, "other")
+		write(core, 3*time.Second, "retrying", zap.Int("attempt", 1))
+		write(core, 4*time.Second, "retrying", zap.Int("attempt", 2))
+		write(core, 5*time.Second, "other")
+		assert.Equal(t, []string{"retrying", "other", "retrying"}, messages(logs.TakeAll()))
+
+		// Duplicates are written once the window expires
+		write(core, 2*time.Minute, "next")
+		entries := logs.TakeAll()
+		require.Len(t, entries, 3)
+		assert.Equal(t, "next", entries[2].Message)
+
+		var retrying, other observer.LoggedEntry
+		for _, e := range entries[:2] {
+			if e.Message == "retrying" {
+				retrying = e
+			} else {
+				other = e
+			}
+		}
+		assert.Equal(t, map[string]interface{}{
+			"attempt":   int64(1),
+			"repeated":  int64(2),
+			"firstSeen": start.Add(1 * time.Second),
+			"lastSeen":  start.Add(3 * time.Second),
+		}, retrying.ContextMap())
+		assert.Equal(t, start.Add(3*time.Second), retrying.Time)
+		assert.Equal(t, int64(1), other.ContextMap()["repeated"])
+	})
+
+	t.Run("consecutive", func(t *testing.T) {
+		observed, logs := observer.New(zapcore.DebugLevel)
+		core := newDedupCore(observed, DedupOptions{Window: time.Minute, Consecutive: true})
+
+		write(core, 0, "retrying")
+		write(core, 1*time.Second, "retrying")
+		write(core, 2*time.Second, "retrying")
+		write(core, 3*time.Second, "other")
+		write(core, 4*time.Second, "retrying")
+
+		entries := logs.TakeAll()
+		assert.Equal(t, []string{"retrying", "retrying", "other", "retrying"}, messages(entries))
+		assert.Equal(t, int64(2), entries[1].ContextMap()["repeated"])
+		assert.NotContains(t, entries[3].ContextMap(), "repeated")
+	})
+
+	t.Run("context", func(t *testing.T) {
+		observed, logs := observer.New(zapcore.DebugLevel)
+		core := newDedupCore(observed, DedupOptions{Window: time.Minute})
+
+		write(core.With([]zapcore.Field{zap.String("user", "a")}), 0, "retrying")
+		write(core.With([]zapcore.Field{zap.String("user", "b")}), 0, "retrying")
+		write(core.With([]zapcore.Field{zap.String("user", "a")}), 0, "retrying")
+		assert.Equal(t, 2, logs.Len())
+
+		// Duplicates are written with their context on sync
+		require.NoError(t, core.Sync())
+		entries := logs.TakeAll()
+		require.Len(t, entries, 3)
+		assert.Equal(t, "a", entries[2].ContextMap()["user"])
+		assert.Equal(t, int64(1), entries[2].ContextMap()["repeated"])
+	})
+}
+
+func messages(entries []observer.LoggedEntry) []string {
+	msgs := make([]string, len(entries))
+	for i, e := range entries {
+		msgs[i] = e.Message
+	}
+	return msgs
+}
@@ -0,0 +1,158 @@
+package outputcore
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+const (
+	// DefaultDropReportInterval is the default interval at which summaries of entries
+	// dropped by sampling are written.
+	DefaultDropReportInterval = time.Minute
+
+	// maxDropKeys is the maximum number of distinct DropKeys that are tracked. Drops
+	// of any further entries are recorded with an empty Scope and Message.
+	maxDropKeys = 1000
+)
+
+// DropKey identifies entries dropped by sampling.
+type DropKey struct {
+	Scope   string
+	Level   zapcore.Level
+	Message string
+}
+
+// Drops records entries dropped by sampling, so that they can be reported. Summaries of
+// the entries dropped for each DropKey are written at most once per interval, after the
+// interval has elapsed when the next entry is checked or the core is synced. Cumulative
+// counts are available with Counts.
+type Drops struct {
+	interval time.Duration
+	// nextReport is the time in Unix nanoseconds after which summaries are due.
+	nextReport atomic.Int64
+
+	mu sync.Mutex
+	// lastReport is when summaries were last written, or when Drops was created.
+	lastReport time.Time
+	pending    map[DropKey]uint64
+	total      map[DropKey]uint64
+	// core is where summaries are written.
+	core zapcore.Core
+}
+
+// NewDrops instantiates Drops that reports summaries every interval. If interval is not
+// positive, DefaultDropReportInterval is used.
+func NewDrops(interval time.Duration) *Drops {
+	if interval <= 0 {
+		interval = DefaultDropReportInterval
+	}
+	now := time.Now()
+	d := &Drops{
+		interval:   interval,
+		lastReport: now,
+		pending:    make(map[DropKey]uint64),
+		total:      make(map[DropKey]uint64),
+	}
+	d.nextReport.Store(now.Add(interval).UnixNano())
+	return d
+}
+
+// Counts returns the cumulative number of dropped entries for each DropKey.
+func (d *Drops) Counts() map[DropKey]uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	counts := make(map[DropKey]uint64, len(d.total))
+	for k, n := range d.total {
+		counts[k] = n
+	}
+	return counts
+}
+
+// record records that ent was dropped.
+func (d *Drops) record(ent zapcore.Entry) {
+	k := DropKey{Scope: ent.LoggerName, Level: ent.Level, Message: ent.Message}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.total[k]; !ok && len(d.total) >= maxDropKeys {
+		k = DropKey{Level: ent.Level}
+	}
+	d.pending[k]++
+	d.total[k]++
+}
+
+// setCore sets the core that summaries are written to, if it is not set yet or force is
+// true.
+func (d *Drops) setCore(core zapcore.Core, force bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.core == nil || force {
+		d.core = core
+	}
+}
+
+// maybeReport writes summaries if they are due at t.
+func (d *Drops) maybeReport(t time.Time) {
+	next := d.nextReport.Load()
+	if t.UnixNano() < next {
+		return
+	}
+	if !d.nextReport.CompareAndSwap(next, t.Add(d.interval).UnixNano()) {
+		return // another goroutine is reporting
+	}
+	d.report(t)
+}
+
+// report writes summaries of all entries dropped since the last report. Summaries state
+// the time elapsed since the last report rather than the interval, since reports are
+// also written on sync and are only due once an entry is checked after the interval.
+func (d *Drops) report(t time.Time) {
+	d.mu.Lock()
+	pending, core := d.pending, d.core
+	if len(pending) > 0 {
+		d.pending = make(map[DropKey]uint64)
+	}
+	elapsed := t.Sub(d.lastReport)
+	d.lastReport = t
+	d.mu.Unlock()
+
+	if elapsed >= time.Second {
+		elapsed = elapsed.Round(time.Second)
+	} else {
+		elapsed = elapsed.Round(time.Millisecond)
+	}
+
+	if len(pending) == 0 || core == nil {
+		return
+	}
+	for k, n := range pending {
+		// Errors have nowhere to go, since this is the log output itself
+		_ = core.Write(zapcore.Entry{
+			LoggerName: k.Scope,
+			Level:      k.Level,
+			Time:       t,
+			Message:    fmt.Sprintf("sampling dropped %d entries in last %s", n, elapsed),
+		}, []zapcore.Field{
+			zap.String("sampledMessage", k.Message),
+			zap.Uint64("dropped", n),
+		})
+	}
+}
+
+// hasResource reports whether fields include the Resource field, which is attached to
+// the root logger.
+func hasResource(fields []zapcore.Field) bool {
+	for _, f := range fields {
+		if f.Key == otelfields.ResourceFieldKey {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package outputcore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+func TestDrops(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	drops := NewDrops(time.Hour)
+	core := newSamplerCore(observed, NewSampling(zap.SamplingConfig{Initial: 1}, nil), drops).
+		With([]zapcore.Field{zap.String(otelfields.ResourceFieldKey, "resource")})
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		for _, scope := range []string{"foo", "bar"} {
+			ent := zapcore.Entry{LoggerName: scope, Level: zapcore.InfoLevel, Message: "hello", Time: now}
+			if ce := core.Check(ent, nil); ce != nil {
+				ce.Write()
+			}
+		}
+	}
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, map[DropKey]uint64{
+		{Scope: "foo", Level: zapcore.InfoLevel, Message: "hello"}: 2,
+		{Scope: "bar", Level: zapcore.InfoLevel, Message: "hello"}: 2,
+	}, drops.Counts())
+
+	t.Run("summaries are written when due", func(t *testing.T) {
+		logs.TakeAll()
+		ent := zapcore.Entry{LoggerName: "baz", Level: zapcore.InfoLevel, Message: "other", Time: now.Add(2 * time.Hour)}
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write()
+		}
+
+		// Summaries state the time elapsed since drops were created
+		summaries := logs.FilterMessage("sampling dropped 2 entries in last 2h0m0s").AllUntimed()
+		require.Len(t, summaries, 2)
+		for _, s := range summaries {
+			assert.Equal(t, zapcore.InfoLevel, s.Level)
+			assert.Contains(t, []string{"foo", "bar"}, s.LoggerName)
+			assert.Equal(t, map[string]interface{}{
+				otelfields.ResourceFieldKey: "resource",
+				"sampledMessage":            "hello",
+				"dropped":                   uint64(2),
+			}, s.ContextMap())
+		}
+		assert.Equal(t, 1, logs.FilterMessage("other").Len())
+	})
+
+	t.Run("summaries are written on sync", func(t *testing.T) {
+		observed, logs := observer.New(zapcore.DebugLevel)
+		drops := NewDrops(time.Hour)
+		core := newSamplerCore(observed, NewSampling(zap.SamplingConfig{Initial: 1}, nil), drops)
+
+		ent := zapcore.Entry{LoggerName: "foo", Level: zapcore.InfoLevel, Message: "hello", Time: time.Now()}
+		for i := 0; i < 3; i++ {
+			if ce := core.Check(ent, nil); ce != nil {
+				ce.Write()
+			}
+		}
+		assert.Equal(t, 1, logs.Len())
+
+		require.NoError(t, core.Sync())
+		summaries := logs.FilterMessageSnippet("sampling dropped 2 entries in last ").AllUntimed()
+		require.Len(t, summaries, 1)
+		// The summary does not claim to cover the whole interval
+		assert.NotContains(t, summaries[0].Message, "1h0m0s")
+
+		// Counts are cumulative
+		ent.Time = time.Now()
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write()
+		}
+		assert.Equal(t, uint64(3), drops.Counts()[DropKey{Scope: "foo", Level: zapcore.InfoLevel, Message: "hello"}])
+
+		// Nothing is written if nothing was dropped
+		require.NoError(t, core.Sync())
+		logs.TakeAll()
+		require.NoError(t, core.Sync())
+		assert.Zero(t, logs.Len())
+	})
+}
@@ -1,18 +1,13 @@
 package outputcore
 
 import (
-	"strings"
-
 	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/scopematch"
 )
 
 // Override allows adjusting the log level for specific scopes.
-type Override struct {
-	// Scope matches any zapcore.Entry with the name Scope or the prefix Scope + ".".
-	Scope string
-	// Level is the level to log at for zapcore.Entry's that match Scope.
-	Level zapcore.Level
-}
+type Override = scopematch.Override
 
 // newOverrideCore will create a core with the specific overrides. newCore is
 // required because we need to adjust the level to allow the overrides to log.
@@ -70,29 +65,17 @@ func (c *overrideCore) With(fields []zapcore.Field) zapcore.Core {
 	}
 }
 
-func (c *overrideCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
-	for _, o := range c.overrides {
-		if !strings.HasPrefix(ent.LoggerName, o.Scope) {
-			continue
-		}
-		// Check that if o.Scope != ent.LoggerName then it is a child logger
-		if len(ent.LoggerName) > len(o.Scope) && ent.LoggerName[len(o.Scope)] != '.' {
-			continue
-		}
-
-		if o.Level.Enabled(ent.Level) {
-			return c.Core.Check(ent, ce)
-		}
-		return ce
+// EnabledFor reports whether lvl is enabled for the given scope.
+func (c *overrideCore) EnabledFor(scope string, lvl zapcore.Level) bool {
+	if o, ok := scopematch.Match(c.overrides, scope); ok {
+		return o.Level.Enabled(lvl)
 	}
+	return c.level.Enabled(lvl)
+}
 
-	if c.level.Enabled(ent.Level) {
+func (c *overrideCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.EnabledFor(ent.LoggerName, ent.Level) {
 		return c.Core.Check(ent, ce)
 	}
-
-	if !c.Core.Enabled(ent.Level) {
-		return ce
-	}
-
 	return ce
 }
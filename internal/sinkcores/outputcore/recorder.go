@@ -0,0 +1,202 @@
+package outputcore
+
+import (
+	"container/list"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/scopematch"
+)
+
+const (
+	// DefaultFlightRecorderSize is the default number of entries buffered per trace or
+	// scope by a flight recorder.
+	DefaultFlightRecorderSize = 100
+
+	// maxFlightRecorderBuffers is the maximum number of traces and scopes that entries
+	// are buffered for. The least recently used buffer is discarded to make room for
+	// new buffers.
+	maxFlightRecorderBuffers = 1000
+)
+
+// FlightRecorderOptions configures a flight recorder.
+type FlightRecorderOptions struct {
+	// Level is the lowest level of entries to buffer.
+	Level zapcore.Level
+	// Size is the maximum number of entries to buffer per trace or scope. Older
+	// entries are discarded when the buffer is full. If not positive,
+	// DefaultFlightRecorderSize is used.
+	Size int
+}
+
+// NewFlightRecorderCore wraps core, which must only enable entries enabled by levels, to
+// buffer entries that are not enabled by levels but are at or above opts.Level. When an
+// entry at Error level or above is written by core, the entries buffered for the same
+// trace are written before it, or for the same scope if the entry has no trace. Written
+// entries have the field "flightRecorder" set to true.
+func NewFlightRecorderCore(core zapcore.Core, levels *scopematch.Levels, opts FlightRecorderOptions) zapcore.Core {
+	if opts.Size <= 0 {
+		opts.Size = DefaultFlightRecorderSize
+	}
+	return &recorderCore{
+		Core:   core,
+		levels: levels,
+		state: &recorderState{
+			opts:    opts,
+			buffers: make(map[string]*list.Element),
+			lru:     list.New(),
+		},
+	}
+}
+
+type recorderCore struct {
+	zapcore.Core
+
+	levels *scopematch.Levels
+	state  *recorderState
+	// traceID is the ID of the trace attached with With, if any.
+	traceID string
+}
+
+type recorderState struct {
+	opts FlightRecorderOptions
+
+	mu sync.Mutex
+	// buffers holds the elements of lru by key, whose values are *recorderBuffer.
+	buffers map[string]*list.Element
+	// lru orders buffers from most to least recently used.
+	lru *list.List
+}
+
+type recorderBuffer struct {
+	key string
+	// entries is a ring buffer of entries, where next is the index to write to.
+	entries []recordedEntry
+	next    int
+	full    bool
+}
+
+type recordedEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// Level returns the lowest level that is buffered or enabled by the wrapped core.
+func (c *recorderCore) Level() zapcore.Level {
+	if l := zapcore.LevelOf(c.Core); l < c.state.opts.Level {
+		return l
+	}
+	return c.state.opts.Level
+}
+
+func (c *recorderCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.state.opts.Level || c.Core.Enabled(lvl)
+}
+
+func (c *recorderCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &recorderCore{
+		Core:    c.Core.With(fields),
+		levels:  c.levels,
+		state:   c.state,
+		traceID: c.traceID,
+	}
+	for _, f := range fields {
+		if t, ok := f.Interface.(*encoders.TraceContextEncoder); ok {
+			clone.traceID = t.TraceID
+		}
+	}
+	return clone
+}
+
+func (c *recorderCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.EnabledFor(ent.LoggerName, ent.Level) {
+		if ent.Level >= c.state.opts.Level {
+			return ce.AddCore(ent, &recordingCore{c})
+		}
+		return ce
+	}
+	if ent.Level >= zapcore.ErrorLevel {
+		// Add the flushing core first, so that buffered entries are written before ent.
+		ce = ce.AddCore(ent, &flushingCore{c})
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// key returns the key of the buffer for ent.
+func (c *recorderCore) key(ent zapcore.Entry) string {
+	if c.traceID != "" {
+		return "trace:" + c.traceID
+	}
+	return "scope:" + ent.LoggerName
+}
+
+// recordingCore buffers entries written to it.
+type recordingCore struct{ *recorderCore }
+
+func (c *recordingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := c.state
+	key := c.key(ent)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b *recorderBuffer
+	if el, ok := s.buffers[key]; ok {
+		s.lru.MoveToFront(el)
+		b = el.Value.(*recorderBuffer)
+	} else {
+		if s.lru.Len() >= maxFlightRecorderBuffers {
+			oldest := s.lru.Back()
+			s.lru.Remove(oldest)
+			delete(s.buffers, oldest.Value.(*recorderBuffer).key)
+		}
+		b = &recorderBuffer{key: key, entries: make([]recordedEntry, s.opts.Size)}
+		s.buffers[key] = s.lru.PushFront(b)
+	}
+
+	b.entries[b.next] = recordedEntry{core: c.Core, ent: ent, fields: fields}
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	return nil
+}
+
+// flushingCore writes buffered entries when written to.
+type flushingCore struct{ *recorderCore }
+
+func (c *flushingCore) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	s := c.state
+	key := c.key(ent)
+
+	s.mu.Lock()
+	el, ok := s.buffers[key]
+	if ok {
+		s.lru.Remove(el)
+		delete(s.buffers, key)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	b := el.Value.(*recorderBuffer)
+	entries := b.entries[:b.next]
+	if b.full {
+		// Oldest entries start at next
+		entries = make([]recordedEntry, 0, len(b.entries))
+		entries = append(entries, b.entries[b.next:]...)
+		entries = append(entries, b.entries[:b.next]...)
+	}
+	for _, e := range entries {
+		fields := make([]zapcore.Field, len(e.fields), len(e.fields)+1)
+		copy(fields, e.fields)
+		// Errors have nowhere to go, since this is the log output itself
+		_ = e.core.Write(e.ent, append(fields, zap.Bool("flightRecorder", true)))
+	}
+	return nil
+}
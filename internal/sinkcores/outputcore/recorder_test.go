@@ -0,0 +1,77 @@
+package outputcore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+	"github.com/sourcegraph/log/internal/scopematch"
+)
+
+func TestFlightRecorderCore(t *testing.T) {
+	newCore := func(size int) (zapcore.Core, *observer.ObservedLogs) {
+		levels := scopematch.NewLevels(zapcore.WarnLevel, nil)
+		observed, logs := observer.New(levels)
+		core := NewFlightRecorderCore(&dynamicCore{Core: observed, levels: levels}, levels, FlightRecorderOptions{
+			Level: zapcore.DebugLevel,
+			Size:  size,
+		})
+		return core, logs
+	}
+	write := func(core zapcore.Core, scope string, level zapcore.Level, msg string) {
+		if ce := core.Check(zapcore.Entry{LoggerName: scope, Level: level, Message: msg}, nil); ce != nil {
+			ce.Write()
+		}
+	}
+	withTrace := func(core zapcore.Core, traceID string) zapcore.Core {
+		return core.With([]zapcore.Field{
+			zap.Inline(&encoders.TraceContextEncoder{TraceContext: otelfields.TraceContext{TraceID: traceID}}),
+		})
+	}
+
+	t.Run("flushes scope on error", func(t *testing.T) {
+		core, logs := newCore(10)
+		assert.True(t, core.Enabled(zapcore.DebugLevel))
+
+		write(core, "foo", zapcore.DebugLevel, "debug 1")
+		write(core, "foo", zapcore.InfoLevel, "info 1")
+		write(core, "bar", zapcore.DebugLevel, "other scope")
+		write(core, "foo", zapcore.WarnLevel, "warn")
+		assert.Equal(t, []string{"warn"}, messages(logs.TakeAll()))
+
+		write(core, "foo", zapcore.ErrorLevel, "error")
+		entries := logs.TakeAll()
+		assert.Equal(t, []string{"debug 1", "info 1", "error"}, messages(entries))
+		assert.Equal(t, true, entries[0].ContextMap()["flightRecorder"])
+		assert.NotContains(t, entries[2].ContextMap(), "flightRecorder")
+
+		// Buffer is cleared after flushing
+		write(core, "foo", zapcore.ErrorLevel, "error")
+		assert.Equal(t, []string{"error"}, messages(logs.TakeAll()))
+	})
+
+	t.Run("flushes trace on error", func(t *testing.T) {
+		core, logs := newCore(10)
+
+		write(withTrace(core, "a"), "foo", zapcore.DebugLevel, "trace a")
+		write(withTrace(core, "b"), "foo", zapcore.DebugLevel, "trace b")
+		write(core, "foo", zapcore.DebugLevel, "no trace")
+		write(withTrace(core, "a"), "bar", zapcore.ErrorLevel, "error")
+		assert.Equal(t, []string{"trace a", "error"}, messages(logs.TakeAll()))
+	})
+
+	t.Run("buffers are bounded", func(t *testing.T) {
+		core, logs := newCore(2)
+
+		write(core, "foo", zapcore.DebugLevel, "debug 1")
+		write(core, "foo", zapcore.DebugLevel, "debug 2")
+		write(core, "foo", zapcore.DebugLevel, "debug 3")
+		write(core, "foo", zapcore.ErrorLevel, "error")
+		assert.Equal(t, []string{"debug 2", "debug 3", "error"}, messages(logs.TakeAll()))
+	})
+}
@@ -0,0 +1,225 @@
+package outputcore
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/scopematch"
+)
+
+const (
+	// samplingTick is the interval over which entries are counted for sampling.
+	samplingTick = time.Second
+
+	numLevels        = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+	countersPerLevel = 4096
+)
+
+// Sampling holds the sampling configuration of cores created with NewDynamicCore. It
+// can be changed at runtime with Set, which takes effect on all cores sharing the
+// Sampling immediately.
+//
+// Like zap's sampler, the first Initial entries with the same scope, level and message in
+// each second are logged, and every Thereafter-th entry after that. Sampling is disabled
+// if Initial is not positive.
+type Sampling struct {
+	state atomic.Pointer[samplingState]
+}
+
+type samplingState struct {
+	config zap.SamplingConfig
+	rules  []SamplingRule
+}
+
+// SamplingRule overrides the sampling configuration for entries from specific scopes
+// and levels.
+type SamplingRule struct {
+	// Scope matches the names of zapcore.Entry, including children of matching scopes.
+	Scope scopematch.Pattern
+	// Level restricts the rule to entries at this level. If nil, the rule applies to
+	// entries at all levels.
+	Level *zapcore.Level
+
+	zap.SamplingConfig
+}
+
+// NewSampling instantiates Sampling with the given default configuration and rules.
+func NewSampling(config zap.SamplingConfig, rules []SamplingRule) *Sampling {
+	s := &Sampling{}
+	s.Set(config, rules)
+	return s
+}
+
+// Set replaces the default sampling configuration and rules.
+func (s *Sampling) Set(config zap.SamplingConfig, rules []SamplingRule) {
+	s.state.Store(&samplingState{
+		config: config,
+		rules:  append([]SamplingRule(nil), rules...),
+	})
+}
+
+// Get returns the current default sampling configuration and rules.
+func (s *Sampling) Get() (zap.SamplingConfig, []SamplingRule) {
+	state := s.state.Load()
+	return state.config, append([]SamplingRule(nil), state.rules...)
+}
+
+// configFor returns the sampling configuration for entries from scope at lvl. If multiple
+// rules match, the rule whose scope matches most specifically is used, preferring rules
+// for a specific level if they match equally specifically.
+func (s *samplingState) configFor(scope string, lvl zapcore.Level) zap.SamplingConfig {
+	var (
+		best      *SamplingRule
+		bestMatch scopematch.Specificity
+	)
+	for i := range s.rules {
+		r := &s.rules[i]
+		if r.Level != nil && *r.Level != lvl {
+			continue
+		}
+		m, ok := r.Scope.Match(scope)
+		if !ok {
+			continue
+		}
+		if best == nil || m.MoreSpecificThan(bestMatch) ||
+			(!bestMatch.MoreSpecificThan(m) && best.Level == nil && r.Level != nil) {
+			best, bestMatch = r, m
+		}
+	}
+	if best == nil {
+		return s.config
+	}
+	return best.SamplingConfig
+}
+
+// scopeEnabler is implemented by cores that enable levels per scope.
+type scopeEnabler interface {
+	EnabledFor(scope string, lvl zapcore.Level) bool
+}
+
+// samplerCore wraps a core to sample entries based on the configuration in sampling,
+// recording dropped entries in drops. Only entries enabled for their scope by the
+// wrapped core are sampled, so that entries it would not log are never counted or
+// recorded as dropped.
+type samplerCore struct {
+	zapcore.Core
+
+	sampling *Sampling
+	counts   *counters
+	drops    *Drops
+}
+
+func newSamplerCore(core zapcore.Core, sampling *Sampling, drops *Drops) *samplerCore {
+	drops.setCore(core, false)
+	return &samplerCore{
+		Core:     core,
+		sampling: sampling,
+		counts:   &counters{},
+		drops:    drops,
+	}
+}
+
+func (c *samplerCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.Core)
+}
+
+func (c *samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	core := c.Core.With(fields)
+	if hasResource(fields) {
+		// Write summaries of dropped entries with the Resource of the root logger.
+		c.drops.setCore(core, true)
+	}
+	return &samplerCore{
+		Core:     core,
+		sampling: c.sampling,
+		counts:   c.counts,
+		drops:    c.drops,
+	}
+}
+
+func (c *samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabledFor(ent) {
+		return ce
+	}
+	c.drops.maybeReport(ent.Time)
+
+	config := c.sampling.state.Load().configFor(ent.LoggerName, ent.Level)
+	if config.Initial > 0 && ent.Level >= zapcore.DebugLevel && ent.Level <= zapcore.FatalLevel {
+		// Count entries per scope, so that chatty scopes do not cause entries with the
+		// same message from other scopes to be dropped.
+		n := c.counts.get(ent.Level, ent.LoggerName, ent.Message).incCheckReset(ent.Time, samplingTick)
+		if n > uint64(config.Initial) &&
+			(config.Thereafter <= 0 || (n-uint64(config.Initial))%uint64(config.Thereafter) != 0) {
+			c.drops.record(ent)
+			return ce
+		}
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// enabledFor reports whether the wrapped core would log ent.
+func (c *samplerCore) enabledFor(ent zapcore.Entry) bool {
+	if s, ok := c.Core.(scopeEnabler); ok {
+		return s.EnabledFor(ent.LoggerName, ent.Level)
+	}
+	return c.Enabled(ent.Level)
+}
+
+// Sync writes summaries of any dropped entries before syncing the wrapped core.
+func (c *samplerCore) Sync() error {
+	c.drops.report(time.Now())
+	return c.Core.Sync()
+}
+
+// counters holds the counters of each level. The counters of a level are only allocated
+// once an entry at that level is sampled, since entries below the enabled level and at
+// levels where sampling is disabled by rules are never counted.
+type counters [numLevels]atomic.Pointer[levelCounters]
+
+type levelCounters [countersPerLevel]counter
+
+func (cs *counters) get(lvl zapcore.Level, scope, message string) *counter {
+	p := &cs[lvl-zapcore.DebugLevel]
+	level := p.Load()
+	if level == nil {
+		// Another goroutine may have allocated the counters concurrently
+		p.CompareAndSwap(nil, &levelCounters{})
+		level = p.Load()
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(scope))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(message))
+	return &level[h.Sum32()%countersPerLevel]
+}
+
+type counter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// incCheckReset increments the counter, resetting it first if tick has elapsed since
+// the last reset.
+func (c *counter) incCheckReset(t time.Time, tick time.Duration) uint64 {
+	tn := t.UnixNano()
+	resetAfter := c.resetAt.Load()
+	if resetAfter > tn {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+
+	newResetAfter := tn + tick.Nanoseconds()
+	if !c.resetAt.CompareAndSwap(resetAfter, newResetAfter) {
+		// We raced with another goroutine trying to reset, and it also reset the
+		// counter to 1, so we need to reincrement the counter.
+		return c.count.Add(1)
+	}
+
+	return 1
+}
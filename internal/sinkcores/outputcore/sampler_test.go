@@ -0,0 +1,65 @@
+package outputcore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sourcegraph/log/internal/scopematch"
+)
+
+func TestSamplerCounters(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	errorLevel := zapcore.ErrorLevel
+	sampling := NewSampling(zap.SamplingConfig{Initial: 1}, []SamplingRule{{
+		Scope: scopematch.MustParse("**"),
+		Level: &errorLevel,
+	}})
+	core := newSamplerCore(observed, sampling, NewDrops(time.Hour))
+
+	now := time.Now()
+	for _, lvl := range []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.InfoLevel, zapcore.ErrorLevel, zapcore.ErrorLevel} {
+		if ce := core.Check(zapcore.Entry{LoggerName: "foo", Level: lvl, Message: "hello", Time: now}, nil); ce != nil {
+			ce.Write()
+		}
+	}
+	assert.Equal(t, 3, logs.Len())
+
+	// Counters are only allocated for levels that are sampled
+	for lvl := zapcore.DebugLevel; lvl <= zapcore.FatalLevel; lvl++ {
+		allocated := core.counts[lvl-zapcore.DebugLevel].Load() != nil
+		assert.Equal(t, lvl == zapcore.InfoLevel, allocated, lvl.String())
+	}
+}
+
+func TestSamplerScopeLevels(t *testing.T) {
+	levels := scopematch.NewLevels(zapcore.InfoLevel, []Override{{
+		Scope: scopematch.MustParse("foo"),
+		Level: zapcore.DebugLevel,
+	}})
+	observed, logs := observer.New(levels)
+	drops := NewDrops(time.Hour)
+	core := newSamplerCore(&dynamicCore{Core: observed, levels: levels},
+		NewSampling(zap.SamplingConfig{Initial: 1}, nil), drops)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		for _, scope := range []string{"foo", "bar"} {
+			if ce := core.Check(zapcore.Entry{LoggerName: scope, Level: zapcore.DebugLevel, Message: "hello", Time: now}, nil); ce != nil {
+				ce.Write()
+			}
+		}
+	}
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "foo", entries[0].LoggerName)
+
+	// Entries below the level of their scope are not recorded as dropped
+	assert.Equal(t, map[DropKey]uint64{
+		{Scope: "foo", Level: zapcore.DebugLevel, Message: "hello"}: 2,
+	}, drops.Counts())
+}
@@ -0,0 +1,178 @@
+package sentrycore
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+// maxBreadcrumbBuffers is the maximum number of traces and scopes that breadcrumbs are
+// kept for. The least recently used breadcrumbs are discarded to make room for new ones.
+const maxBreadcrumbBuffers = 1000
+
+// BreadcrumbOptions configures the recording of entries below the reported level as
+// breadcrumbs, which are attached to reports of errors from the same trace, or from the
+// same scope or its parents if the entry has no trace.
+type BreadcrumbOptions struct {
+	// Level is the lowest level of entries to record.
+	Level zapcore.Level
+	// MaxCount is the maximum number of breadcrumbs to attach to a report. If not
+	// positive, breadcrumbs are not recorded.
+	MaxCount int
+	// MaxAge is the maximum age of breadcrumbs attached to a report. If not positive,
+	// breadcrumbs of any age are attached.
+	MaxAge time.Duration
+}
+
+// breadcrumbs records breadcrumbs by trace and scope.
+type breadcrumbs struct {
+	opts atomic.Pointer[BreadcrumbOptions]
+
+	mu sync.Mutex
+	// buffers holds the elements of lru by key, whose values are *breadcrumbBuffer.
+	buffers map[string]*list.Element
+	// lru orders buffers from most to least recently used.
+	lru *list.List
+}
+
+type breadcrumbBuffer struct {
+	key    string
+	crumbs []*sentry.Breadcrumb
+}
+
+func newBreadcrumbs() *breadcrumbs {
+	b := &breadcrumbs{
+		buffers: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+	b.opts.Store(&BreadcrumbOptions{})
+	return b
+}
+
+// set replaces the options, discarding recorded breadcrumbs.
+func (b *breadcrumbs) set(opts BreadcrumbOptions) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.opts.Store(&opts)
+	b.buffers = make(map[string]*list.Element)
+	b.lru.Init()
+}
+
+// enabled reports whether entries at level are recorded.
+func (b *breadcrumbs) enabled(level zapcore.Level) bool {
+	opts := b.opts.Load()
+	return opts.MaxCount > 0 && level >= opts.Level
+}
+
+// add records a breadcrumb under key.
+func (b *breadcrumbs) add(key string, crumb *sentry.Breadcrumb) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	maxCount := b.opts.Load().MaxCount
+	if maxCount <= 0 {
+		return
+	}
+
+	var buf *breadcrumbBuffer
+	if el, ok := b.buffers[key]; ok {
+		b.lru.MoveToFront(el)
+		buf = el.Value.(*breadcrumbBuffer)
+	} else {
+		if b.lru.Len() >= maxBreadcrumbBuffers {
+			oldest := b.lru.Back()
+			b.lru.Remove(oldest)
+			delete(b.buffers, oldest.Value.(*breadcrumbBuffer).key)
+		}
+		buf = &breadcrumbBuffer{key: key}
+		b.buffers[key] = b.lru.PushFront(buf)
+	}
+
+	if len(buf.crumbs) >= maxCount {
+		// Drop the oldest breadcrumbs
+		buf.crumbs = append(buf.crumbs[:0], buf.crumbs[len(buf.crumbs)-maxCount+1:]...)
+	}
+	buf.crumbs = append(buf.crumbs, crumb)
+}
+
+// get returns the most recent breadcrumbs recorded under any of keys that are not older
+// than the configured maximum age at t, from oldest to newest.
+func (b *breadcrumbs) get(keys []string, t time.Time) []*sentry.Breadcrumb {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	opts := b.opts.Load()
+	var crumbs []*sentry.Breadcrumb
+	for _, key := range keys {
+		if el, ok := b.buffers[key]; ok {
+			for _, crumb := range el.Value.(*breadcrumbBuffer).crumbs {
+				if opts.MaxAge > 0 && t.Sub(crumb.Timestamp) > opts.MaxAge {
+					continue
+				}
+				crumbs = append(crumbs, crumb)
+			}
+		}
+	}
+	sort.SliceStable(crumbs, func(i, j int) bool { return crumbs[i].Timestamp.Before(crumbs[j].Timestamp) })
+	if len(crumbs) > opts.MaxCount {
+		crumbs = crumbs[len(crumbs)-opts.MaxCount:]
+	}
+	return crumbs
+}
+
+// breadcrumbKeys returns the keys that breadcrumbs are recorded under for an entry from
+// scope with the given trace: the trace if there is one, or else the scope and each of
+// its parents.
+func breadcrumbKeys(traceID, scope string) []string {
+	if traceID != "" {
+		return []string{"trace:" + traceID}
+	}
+	keys := []string{"scope:" + scope}
+	for i := strings.LastIndex(scope, "."); i > 0; i = strings.LastIndex(scope, ".") {
+		scope = scope[:i]
+		keys = append(keys, "scope:"+scope)
+	}
+	return keys
+}
+
+// breadcrumbCore records entries written to it as breadcrumbs.
+type breadcrumbCore struct{ *Core }
+
+func (c *breadcrumbCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fs := range [][]zapcore.Field{c.base.Fields, fields} {
+		for _, f := range fs {
+			if f.Key == otelfields.ResourceFieldKey {
+				continue
+			}
+			if _, ok := f.Interface.(*encoders.TraceContextEncoder); ok {
+				continue
+			}
+			f.AddTo(enc)
+		}
+	}
+	for _, err := range c.errs {
+		enc.AddString("error", err.Error())
+	}
+
+	// Only the entry's own scope is needed to record the breadcrumb
+	key := breadcrumbKeys(c.traceID, entry.LoggerName)[0]
+	c.crumbs.add(key, &sentry.Breadcrumb{
+		Type:      "default",
+		Category:  entry.LoggerName,
+		Message:   entry.Message,
+		Data:      enc.Fields,
+		Level:     sentryLevel(entry.Level),
+		Timestamp: entry.Time,
+	})
+	return nil
+}
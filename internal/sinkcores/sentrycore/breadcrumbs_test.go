@@ -0,0 +1,29 @@
+package sentrycore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestBreadcrumbsMaxAge(t *testing.T) {
+	b := newBreadcrumbs()
+	b.set(BreadcrumbOptions{Level: zapcore.DebugLevel, MaxCount: 10, MaxAge: time.Minute})
+
+	now := time.Now()
+	b.add("scope:foo", &sentry.Breadcrumb{Message: "old", Timestamp: now.Add(-2 * time.Minute)})
+	b.add("scope:foo", &sentry.Breadcrumb{Message: "recent", Timestamp: now.Add(-30 * time.Second)})
+
+	crumbs := b.get([]string{"scope:foo"}, now)
+	if assert.Len(t, crumbs, 1) {
+		assert.Equal(t, "recent", crumbs[0].Message)
+	}
+}
+
+func TestBreadcrumbKeys(t *testing.T) {
+	assert.Equal(t, []string{"trace:abc"}, breadcrumbKeys("abc", "foo.bar"))
+	assert.Equal(t, []string{"scope:foo.bar.baz", "scope:foo.bar", "scope:foo"}, breadcrumbKeys("", "foo.bar.baz"))
+}
@@ -1,10 +1,13 @@
 package sentrycore
 
 import (
+	"sync/atomic"
+
 	"github.com/getsentry/sentry-go"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/scopematch"
 )
 
 const (
@@ -18,6 +21,8 @@ type baseContext struct {
 	Scope   string
 	Level   zapcore.Level
 	Message string
+	// Fingerprint is the fingerprint set with a fingerprint field, if any.
+	Fingerprint Fingerprint
 
 	Fields []zapcore.Field
 }
@@ -33,7 +38,12 @@ func (b *baseContext) clone() *baseContext {
 // errorContext is an error and its associated context that is accumulated during the core lifetime.
 type errorContext struct {
 	*baseContext
+	// Error is the error to report. If nil, the log message is reported as a message event.
 	Error error
+	// Breadcrumbs are the entries recorded before the error.
+	Breadcrumbs []*sentry.Breadcrumb
+	// Grouping is the grouping policy when the report was queued.
+	Grouping Grouping
 }
 
 // Core turns any log message that comes with at least one error into one or more error reports. All
@@ -43,6 +53,19 @@ type Core struct {
 	// errs accumulates the errors fed to the core as attributes.
 	errs []error
 	w    *worker
+	// levels determines which entries are reported, and is shared by all clones.
+	levels *scopematch.Levels
+	// captureMessages indicates if entries at Error level or above without errors are
+	// reported as message events, and is shared by all clones.
+	captureMessages *atomic.Bool
+	// crumbs records entries below the reported level, and is shared by all clones.
+	crumbs *breadcrumbs
+	// grouping is the Grouping policy for reports, and is shared by all clones.
+	grouping *atomic.Int32
+	// limits rate limits similar reports, and is shared by all clones.
+	limits *limiter
+	// traceID is the ID of the trace attached with With, if any.
+	traceID string
 }
 
 var _ zapcore.Core = &Core{}
@@ -51,12 +74,23 @@ var _ zapcore.Core = &Core{}
 // this core onto the global logger that is then used to create scoped loggers in other parts of the codebase.
 func NewCore(hub *sentry.Hub) *Core {
 	w := &worker{
-		hub:  sentryHub{hub: hub.Clone()}, // Avoid accidental side effects if the hub is modified elsewhere.
-		C:    make(chan *errorContext, bufferSize),
-		done: make(chan struct{}),
+		hub:    sentryHub{hub: hub.Clone()}, // Avoid accidental side effects if the hub is modified elsewhere.
+		C:      make(chan *errorContext, bufferSize),
+		done:   make(chan struct{}),
+		stats:  &stats{},
+		limits: newLimiter(),
+
+		deliveryTimeout: sentryTimeout,
 	}
 	w.start()
-	return &Core{w: w}
+	return &Core{
+		w:               w,
+		levels:          scopematch.NewLevels(zapcore.ErrorLevel, nil),
+		captureMessages: &atomic.Bool{},
+		crumbs:          newBreadcrumbs(),
+		grouping:        &atomic.Int32{},
+		limits:          w.limits,
+	}
 }
 
 // Core returns the underlying zapcore.
@@ -64,6 +98,56 @@ func (c *Core) Core() zapcore.Core {
 	return c
 }
 
+// SetLevels replaces the minimum level at which entries with errors are reported, which
+// is Error by default, and the scope overrides that adjust it for specific scopes.
+func (c *Core) SetLevels(level zapcore.Level, overrides []scopematch.Override) {
+	c.levels.Set(level, overrides)
+}
+
+// SetCaptureMessages sets whether entries at Error level or above that do not have any
+// errors are reported as message events. They are not reported by default.
+func (c *Core) SetCaptureMessages(capture bool) {
+	c.captureMessages.Store(capture)
+}
+
+// SetBreadcrumbs replaces the options for recording entries below the reported level as
+// breadcrumbs. Breadcrumbs are not recorded by default.
+func (c *Core) SetBreadcrumbs(opts BreadcrumbOptions) {
+	c.crumbs.set(opts)
+}
+
+// Breadcrumbs returns the current options for recording breadcrumbs.
+func (c *Core) Breadcrumbs() BreadcrumbOptions {
+	return *c.crumbs.opts.Load()
+}
+
+// SetGrouping replaces the policy for grouping reports of entries without a fingerprint
+// field into Sentry issues, which is GroupDefault by default.
+func (c *Core) SetGrouping(grouping Grouping) {
+	c.grouping.Store(int32(grouping))
+}
+
+// Grouping returns the current policy for grouping reports.
+func (c *Core) Grouping() Grouping {
+	return Grouping(c.grouping.Load())
+}
+
+// SetRateLimit replaces the options for rate limiting similar reports, discarding the
+// state of current rate limits. Reports are not rate limited by default.
+func (c *Core) SetRateLimit(opts RateLimitOptions) {
+	c.limits.set(opts)
+}
+
+// RateLimit returns the current options for rate limiting similar reports.
+func (c *Core) RateLimit() RateLimitOptions {
+	return *c.limits.opts.Load()
+}
+
+// Stats returns cumulative counts of the reports handled by the core and its clones.
+func (c *Core) Stats() Stats {
+	return c.w.stats.snapshot()
+}
+
 // SetHub replaces the sentry.Hub used to submit sentry error reports.
 func (c *Core) SetHub(hub *sentry.Hub) {
 	c.w.setHub(hub)
@@ -74,9 +158,15 @@ func (c *Core) SetHub(hub *sentry.Hub) {
 // the same across cores.
 func (c *Core) clone() *Core {
 	clo := Core{
-		w:    c.w,
-		base: *c.base.clone(),
-		errs: make([]error, len(c.errs)),
+		w:               c.w,
+		levels:          c.levels,
+		captureMessages: c.captureMessages,
+		crumbs:          c.crumbs,
+		grouping:        c.grouping,
+		limits:          c.limits,
+		traceID:         c.traceID,
+		base:            *c.base.clone(),
+		errs:            make([]error, len(c.errs)),
 	}
 	copy(clo.errs, c.errs)
 
@@ -97,6 +187,13 @@ func (c *Core) With(fields []zapcore.Field) zapcore.Core {
 				continue
 			}
 		}
+		if fingerprint, ok := fingerprintFrom(f); ok {
+			c.base.Fingerprint = fingerprint
+			continue
+		}
+		if t, ok := f.Interface.(*encoders.TraceContextEncoder); ok {
+			c.traceID = t.TraceID
+		}
 		c.base.Fields = append(c.base.Fields, f)
 	}
 	return c
@@ -104,8 +201,10 @@ func (c *Core) With(fields []zapcore.Field) zapcore.Core {
 
 // Check inspects e to see if it needs to be sent to Sentry.
 func (c *Core) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
-	if c.Enabled(e.Level) {
+	if c.levels.EnabledFor(e.LoggerName, e.Level) {
 		return ce.AddCore(e, c)
+	} else if c.crumbs.enabled(e.Level) {
+		return ce.AddCore(e, &breadcrumbCore{c})
 	} else {
 		return ce
 	}
@@ -132,27 +231,56 @@ func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 				continue
 			}
 		}
+		if fingerprint, ok := fingerprintFrom(f); ok {
+			base.Fingerprint = fingerprint
+			continue
+		}
 		base.Fields = append(base.Fields, f)
 	}
 
+	if len(errs) == 0 && entry.Level >= zapcore.ErrorLevel && c.captureMessages.Load() {
+		// Report the entry itself as a message event.
+		errs = append(errs, nil)
+	}
+
+	if len(errs) == 0 {
+		if c.crumbs.enabled(entry.Level) {
+			// Nothing to report, but the entry is still useful context for later reports.
+			return (&breadcrumbCore{c}).Write(entry, fields)
+		}
+		return nil
+	}
+	crumbs := c.crumbs.get(breadcrumbKeys(c.traceID, entry.LoggerName), entry.Time)
+	grouping := c.Grouping()
+
 	for _, err := range errs {
-		errC := errorContext{baseContext: base, Error: err}
-		select {
-		case c.w.C <- &errC:
-		default: // if we can't queue, just drop the errors.
+		errC := &errorContext{baseContext: base, Error: err, Breadcrumbs: crumbs, Grouping: grouping}
+		if c.limits.allow(errC, entry.Time) {
+			c.queue(errC)
+		} else {
+			c.w.stats.rateLimited.Add(1)
 		}
 	}
 	return nil
 }
 
-// Enabled returns false when the log level is below the Error level.
+// queue queues errC to be reported.
+func (c *Core) queue(errC *errorContext) {
+	c.w.queue(errC)
+}
+
+// Enabled returns false when the log level is below the Error level, or below the
+// lowest level of any scope overrides or breadcrumbs.
 func (c *Core) Enabled(level zapcore.Level) bool {
-	return level >= zapcore.ErrorLevel
+	return c.levels.Enabled(level) || c.crumbs.enabled(level)
 }
 
 // Sync ensure that the remaining event are flushed, but has a hard limit of TODO seconds
 // after which it will stop blocking to avoid interruping application shutdown.
 func (c *Core) Sync() error {
+	for _, summary := range c.limits.summarize() {
+		c.queue(summary)
+	}
 	return c.w.Flush()
 }
 
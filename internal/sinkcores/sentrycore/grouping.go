@@ -0,0 +1,78 @@
+package sentrycore
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// FingerprintFieldKey is the key of fields created by FingerprintField.
+const FingerprintFieldKey = "sentry.fingerprint"
+
+// Fingerprint is a Sentry fingerprint, which determines how events are grouped into
+// issues. The "{{ default }}" element refers to Sentry's default grouping.
+//
+// See https://docs.sentry.io/platforms/go/usage/sdk-fingerprinting/
+type Fingerprint []string
+
+// FingerprintField returns a field that sets the fingerprint of the reports of an entry.
+// The field is skipped by other cores.
+func FingerprintField(fingerprint ...string) zapcore.Field {
+	return zapcore.Field{
+		Key:       FingerprintFieldKey,
+		Type:      zapcore.SkipType,
+		Interface: Fingerprint(fingerprint),
+	}
+}
+
+// fingerprintFrom returns the fingerprint carried by f, if f is a fingerprint field.
+func fingerprintFrom(f zapcore.Field) (Fingerprint, bool) {
+	if f.Key != FingerprintFieldKey || f.Type != zapcore.SkipType {
+		return nil, false
+	}
+	fingerprint, ok := f.Interface.(Fingerprint)
+	return fingerprint, ok
+}
+
+// Grouping is a policy for grouping reports into Sentry issues, which applies to reports
+// of entries without a fingerprint field.
+type Grouping int32
+
+const (
+	// GroupDefault leaves grouping to Sentry, which groups reports by their stack trace,
+	// or by their message if they have none.
+	GroupDefault Grouping = iota
+	// GroupByScopeMessage groups reports by the scope and message of the entry.
+	GroupByScopeMessage
+	// GroupByErrorType groups reports by the scope of the entry and the type of the
+	// cause of the error.
+	GroupByErrorType
+	// GroupByCause groups reports by the scope of the entry and the message of the cause
+	// of the error.
+	GroupByCause
+)
+
+// fingerprint returns the fingerprint of the report of errCtx, or nil if grouping is left
+// to Sentry. Message events without an error are grouped by scope and message with all
+// policies other than GroupDefault.
+func (errCtx *errorContext) fingerprint() []string {
+	if errCtx.Fingerprint != nil {
+		return errCtx.Fingerprint
+	}
+	switch errCtx.Grouping {
+	case GroupByScopeMessage:
+		return []string{errCtx.Scope, errCtx.Message}
+	case GroupByErrorType:
+		if errCtx.Error == nil {
+			return []string{errCtx.Scope, errCtx.Message}
+		}
+		return []string{errCtx.Scope, fmt.Sprintf("%T", errors.Cause(errCtx.Error))}
+	case GroupByCause:
+		if errCtx.Error == nil {
+			return []string{errCtx.Scope, errCtx.Message}
+		}
+		return []string{errCtx.Scope, errors.Cause(errCtx.Error).Error()}
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/sourcegraph/log/internal/configurable"
 	"github.com/sourcegraph/log/internal/encoders"
 	"github.com/sourcegraph/log/internal/otelfields"
+	"github.com/sourcegraph/log/internal/scopematch"
 	"github.com/sourcegraph/log/internal/sinkcores/sentrycore"
 	"github.com/sourcegraph/log/logtest"
 	"github.com/stretchr/testify/assert"
@@ -78,6 +79,350 @@ func TestLevelFiltering(t *testing.T) {
 	})
 }
 
+func TestScopeOverrides(t *testing.T) {
+	e := errors.New("test error")
+	tt := []struct {
+		scope      string
+		level      zapcore.Level
+		wantReport bool
+	}{
+		{scope: "noisy", level: zapcore.ErrorLevel, wantReport: false},
+		{scope: "critical", level: zapcore.InfoLevel, wantReport: false},
+		{scope: "critical", level: zapcore.WarnLevel, wantReport: true},
+		{scope: "critical.child", level: zapcore.WarnLevel, wantReport: true},
+		{scope: "other", level: zapcore.WarnLevel, wantReport: false},
+		{scope: "other", level: zapcore.ErrorLevel, wantReport: true},
+	}
+	for _, test := range tt {
+		t.Run(fmt.Sprintf("%s %s", test.scope, test.level.CapitalString()), func(t *testing.T) {
+			logger, tr, sync := newTestLogger(t, scopematch.Override{
+				Scope: scopematch.MustParse("**.noisy"),
+				Level: zapcore.FatalLevel + 1,
+			}, scopematch.Override{
+				Scope: scopematch.MustParse("**.critical"),
+				Level: zapcore.WarnLevel,
+			})
+			logWithLevel(logger.Scoped(test.scope), test.level, "msg", log.Error(e))
+			var count int
+			if test.wantReport {
+				count = 1
+			}
+			sync()
+			assert.Len(t, tr.Events(), count)
+		})
+	}
+
+	t.Run("FATAL no report when disabled", func(t *testing.T) {
+		hub, _ := newTestHub(t)
+		core := sentrycore.NewCore(hub)
+		core.SetLevels(zapcore.ErrorLevel, []scopematch.Override{{
+			Scope: scopematch.MustParse("noisy"),
+			Level: zapcore.FatalLevel + 1,
+		}})
+		ce := core.Check(zapcore.Entry{LoggerName: "noisy", Level: zapcore.FatalLevel}, nil)
+		assert.Nil(t, ce)
+		assert.True(t, core.Enabled(zapcore.FatalLevel))
+	})
+}
+
+func TestCaptureLevel(t *testing.T) {
+	e := errors.New("test error")
+	transport := &sentrycore.TransportMock{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+	require.NoError(t, err)
+	core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+	core.SetLevels(zapcore.WarnLevel, nil)
+
+	assert.True(t, core.Enabled(zapcore.WarnLevel))
+	assert.False(t, core.Enabled(zapcore.InfoLevel))
+
+	logger := configurable.Cast(logtest.Scoped(t)).WithCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	})
+	logger.Info("info", log.Error(e))
+	logger.Warn("warn", log.Error(e))
+	require.NoError(t, core.Sync())
+	if assert.Len(t, transport.Events(), 1) {
+		assert.Equal(t, sentry.LevelWarning, transport.Events()[0].Level)
+	}
+}
+
+func TestCaptureMessages(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		logger, tr, sync := newTestLogger(t)
+		logger.Error("should never happen")
+		sync()
+		assert.Empty(t, tr.Events())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		transport := &sentrycore.TransportMock{}
+		client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+		require.NoError(t, err)
+		core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+		core.SetLevels(zapcore.WarnLevel, nil)
+		core.SetCaptureMessages(true)
+
+		logger := configurable.Cast(logtest.Scoped(t)).WithCore(func(c zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(c, core)
+		}).Scoped("my-scope")
+		logger.Warn("only errors are captured as messages")
+		logger.Error("should never happen", log.String("foo", "bar"))
+		logger.Error("with error", log.Error(errors.New("test error")))
+		require.NoError(t, core.Sync())
+
+		events := transport.Events()
+		require.Len(t, events, 2)
+		var message, withError *sentry.Event
+		for _, e := range events {
+			if len(e.Exception) > 0 {
+				withError = e
+			} else {
+				message = e
+			}
+		}
+		require.NotNil(t, message)
+		require.NotNil(t, withError)
+		assert.Equal(t, "[TestCaptureMessages/enabled.my-scope] should never happen", message.Message)
+		assert.Equal(t, sentry.LevelError, message.Level)
+		assert.Equal(t, "bar", message.Contexts["log"]["foo"])
+		assert.Equal(t, "TestCaptureMessages/enabled.my-scope", message.Tags["scope"])
+	})
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	e := errors.New("test error")
+	newLogger := func(t *testing.T, opts sentrycore.BreadcrumbOptions) (log.Logger, *sentrycore.TransportMock, func()) {
+		transport := &sentrycore.TransportMock{}
+		client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+		require.NoError(t, err)
+		core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+		core.SetBreadcrumbs(opts)
+
+		// Use a logger without the test name in the scope
+		logger := configurable.Cast(log.NoOp()).WithCore(func(zapcore.Core) zapcore.Core { return core })
+		return logger, transport, func() { require.NoError(t, core.Sync()) }
+	}
+	crumbMessages := func(e *sentry.Event) []string {
+		var msgs []string
+		for _, c := range e.Breadcrumbs {
+			msgs = append(msgs, c.Category+": "+c.Message)
+		}
+		return msgs
+	}
+
+	t.Run("scope lineage", func(t *testing.T) {
+		logger, tr, sync := newLogger(t, sentrycore.BreadcrumbOptions{Level: zapcore.InfoLevel, MaxCount: 3})
+		logger.Scoped("foo").Info("parent", log.String("k", "v"))
+		logger.Scoped("foo").Debug("too verbose")
+		logger.Scoped("bar").Info("other scope")
+		logger.Scoped("foo").Scoped("baz").Warn("child")
+		logger.Scoped("foo").Scoped("baz").Error("error without error field")
+		logger.Scoped("foo").Scoped("baz").Error("failed", log.Error(e))
+		sync()
+
+		require.Len(t, tr.Events(), 1)
+		event := tr.Events()[0]
+		assert.Equal(t, []string{
+			"foo: parent",
+			"foo.baz: child",
+			"foo.baz: error without error field",
+		}, crumbMessages(event))
+		assert.Equal(t, sentry.LevelInfo, event.Breadcrumbs[0].Level)
+		assert.Equal(t, map[string]interface{}{"k": "v"}, event.Breadcrumbs[0].Data)
+	})
+
+	t.Run("trace", func(t *testing.T) {
+		logger, tr, sync := newLogger(t, sentrycore.BreadcrumbOptions{Level: zapcore.DebugLevel, MaxCount: 10})
+		logger.Scoped("foo").WithTrace(log.TraceContext{TraceID: "a"}).Debug("trace a")
+		logger.Scoped("bar").WithTrace(log.TraceContext{TraceID: "b"}).Debug("trace b")
+		logger.Scoped("baz").Debug("no trace")
+		logger.Scoped("baz").WithTrace(log.TraceContext{TraceID: "a"}).Error("failed", log.Error(e))
+		sync()
+
+		require.Len(t, tr.Events(), 1)
+		assert.Equal(t, []string{"foo: trace a"}, crumbMessages(tr.Events()[0]))
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		logger, tr, sync := newLogger(t, sentrycore.BreadcrumbOptions{Level: zapcore.DebugLevel, MaxCount: 2, MaxAge: time.Minute})
+		for _, msg := range []string{"1", "2", "3"} {
+			logger.Scoped("foo").Debug(msg)
+		}
+		logger.Scoped("foo").Error("failed", log.Error(e))
+		sync()
+
+		require.Len(t, tr.Events(), 1)
+		assert.Equal(t, []string{"foo: 2", "foo: 3"}, crumbMessages(tr.Events()[0]))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		logger, tr, sync := newLogger(t, sentrycore.BreadcrumbOptions{})
+		logger.Scoped("foo").Warn("warning")
+		logger.Scoped("foo").Error("failed", log.Error(e))
+		sync()
+
+		require.Len(t, tr.Events(), 1)
+		assert.Empty(t, tr.Events()[0].Breadcrumbs)
+	})
+}
+
+type idError struct{ id int }
+
+func (e *idError) Error() string { return fmt.Sprintf("record %d not found", e.id) }
+
+func TestGrouping(t *testing.T) {
+	newLogger := func(t *testing.T, grouping sentrycore.Grouping) (log.Logger, *sentrycore.TransportMock, func()) {
+		transport := &sentrycore.TransportMock{}
+		client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+		require.NoError(t, err)
+		core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+		core.SetCaptureMessages(true)
+		core.SetGrouping(grouping)
+
+		logger := configurable.Cast(log.NoOp()).WithCore(func(zapcore.Core) zapcore.Core { return core })
+		return logger.Scoped("foo"), transport, func() { require.NoError(t, core.Sync()) }
+	}
+	e := fmt.Errorf("lookup: %w", &idError{id: 42})
+
+	for _, tc := range []struct {
+		name     string
+		grouping sentrycore.Grouping
+		want     []string
+	}{
+		{name: "default", grouping: sentrycore.GroupDefault, want: nil},
+		{name: "scope message", grouping: sentrycore.GroupByScopeMessage, want: []string{"foo", "failed"}},
+		{name: "error type", grouping: sentrycore.GroupByErrorType, want: []string{"foo", "*sentrycore_test.idError"}},
+		{name: "cause", grouping: sentrycore.GroupByCause, want: []string{"foo", "record 42 not found"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, tr, sync := newLogger(t, tc.grouping)
+			logger.Error("failed", log.Error(e))
+			sync()
+
+			require.Len(t, tr.Events(), 1)
+			assert.Equal(t, tc.want, tr.Events()[0].Fingerprint)
+		})
+	}
+
+	t.Run("message events", func(t *testing.T) {
+		logger, tr, sync := newLogger(t, sentrycore.GroupByCause)
+		logger.Error("no error")
+		sync()
+
+		require.Len(t, tr.Events(), 1)
+		assert.Equal(t, []string{"foo", "no error"}, tr.Events()[0].Fingerprint)
+	})
+
+	t.Run("fingerprint field", func(t *testing.T) {
+		logger, tr, sync := newLogger(t, sentrycore.GroupByScopeMessage)
+		logger.Error("per call", log.Error(e), log.SentryFingerprint("custom", "{{ default }}"))
+		logger.With(log.SentryFingerprint("with")).Error("accumulated", log.Error(e))
+		sync()
+
+		events := tr.Events()
+		require.Len(t, events, 2)
+		fingerprints := map[string][]string{}
+		for _, event := range events {
+			fingerprints[event.Exception[len(event.Exception)-1].Type] = event.Fingerprint
+			assert.NotContains(t, event.Contexts["log"], sentrycore.FingerprintFieldKey)
+		}
+		assert.Equal(t, map[string][]string{
+			"[foo] per call: record 42 not found":    {"custom", "{{ default }}"},
+			"[foo] accumulated: record 42 not found": {"with"},
+		}, fingerprints)
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	transport := &sentrycore.TransportMock{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+	require.NoError(t, err)
+	core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+	core.SetRateLimit(sentrycore.RateLimitOptions{Burst: 2, Every: time.Hour})
+
+	logger := configurable.Cast(log.NoOp()).WithCore(func(zapcore.Core) zapcore.Core { return core }).Scoped("foo")
+	for i := 0; i < 5; i++ {
+		logger.Error("noisy", log.Error(errors.New("boom")), log.Int("i", i))
+	}
+	logger.Error("quiet", log.Error(errors.New("boom")))
+	require.NoError(t, core.Sync())
+
+	var reported []string
+	var summary *sentry.Event
+	for _, e := range transport.Events() {
+		if len(e.Exception) > 0 {
+			reported = append(reported, e.Exception[len(e.Exception)-1].Type)
+		} else {
+			summary = e
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"[foo] noisy: boom",
+		"[foo] noisy: boom",
+		"[foo] quiet: boom",
+	}, reported)
+	require.NotNil(t, summary)
+	assert.Equal(t, "[foo] suppressed 3 similar events in last 1m0s: noisy", summary.Message)
+	assert.Equal(t, []string{"suppressed", "foo", "noisy", "boom"}, summary.Fingerprint)
+	assert.EqualValues(t, 3, summary.Contexts["log"]["suppressed"])
+	assert.EqualValues(t, 4, summary.Contexts["log"]["i"])
+}
+
+func TestRateLimitSummaryInterval(t *testing.T) {
+	transport := &sentrycore.TransportMock{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+	require.NoError(t, err)
+	core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+	t.Cleanup(core.Stop)
+	core.SetRateLimit(sentrycore.RateLimitOptions{Burst: 1, Every: time.Hour, SummaryInterval: 100 * time.Millisecond})
+
+	logger := configurable.Cast(log.NoOp()).WithCore(func(zapcore.Core) zapcore.Core { return core }).Scoped("foo")
+	for i := 0; i < 3; i++ {
+		logger.Error("noisy", log.Error(errors.New("boom")))
+	}
+
+	// The summary is sent without further reports or Sync
+	assert.Eventually(t, func() bool {
+		for _, e := range transport.Events() {
+			if e.Message == "[foo] suppressed 2 similar events in last 100ms: noisy" {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestStats(t *testing.T) {
+	transport := &sentrycore.TransportMock{}
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Transport: transport,
+		BeforeSend: func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			if event.Tags["scope"] == "discarded" {
+				return nil
+			}
+			return event
+		},
+	})
+	require.NoError(t, err)
+	core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+	core.SetRateLimit(sentrycore.RateLimitOptions{Burst: 1, Every: time.Hour})
+
+	logger := configurable.Cast(log.NoOp()).WithCore(func(zapcore.Core) zapcore.Core { return core })
+	for i := 0; i < 3; i++ {
+		logger.Scoped("foo").Error("failed", log.Error(errors.New("boom")))
+	}
+	logger.Scoped("discarded").Error("failed", log.Error(errors.New("boom")))
+	require.NoError(t, core.Sync())
+
+	assert.Equal(t, sentrycore.Stats{
+		Queued:      3, // including the summary of suppressed reports
+		RateLimited: 2,
+		Sent:        2,
+		Discarded:   1,
+	}, core.Stats())
+}
+
 func TestTags(t *testing.T) {
 	e := errors.New("test error")
 	t.Run("scope", func(t *testing.T) {
@@ -302,12 +647,13 @@ func logWithLevel(logger log.Logger, level zapcore.Level, msg string, fields ...
 	}
 }
 
-func newTestLogger(t testing.TB) (log.Logger, *sentrycore.TransportMock, func()) {
+func newTestLogger(t testing.TB, overrides ...scopematch.Override) (log.Logger, *sentrycore.TransportMock, func()) {
 	transport := &sentrycore.TransportMock{}
 	client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
 	require.NoError(t, err)
 
 	core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+	core.SetLevels(zapcore.ErrorLevel, overrides)
 
 	cl := configurable.Cast(logtest.Scoped(t))
 
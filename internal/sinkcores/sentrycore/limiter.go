@@ -0,0 +1,198 @@
+package sentrycore
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSummaryInterval is the default interval at which summaries of suppressed
+	// reports are sent.
+	DefaultSummaryInterval = time.Minute
+
+	// maxLimitKeys is the maximum number of distinct groups of similar reports that rate
+	// limits are tracked for. Reports in any further groups share a single rate limit.
+	maxLimitKeys = 1000
+)
+
+// RateLimitOptions configures a token bucket rate limit for similar reports, which are
+// reports of entries from the same scope with the same message and error cause. Reports
+// over the limit are suppressed, and summarized periodically.
+type RateLimitOptions struct {
+	// Burst is the number of similar reports that are sent before they are limited.
+	Burst int
+	// Every is the interval at which another similar report is allowed once the burst
+	// is used up. Reports are only limited if both Burst and Every are positive.
+	Every time.Duration
+	// SummaryInterval is the interval at which summaries of suppressed reports are
+	// sent. If not positive, DefaultSummaryInterval is used.
+	SummaryInterval time.Duration
+}
+
+func (o *RateLimitOptions) enabled() bool { return o.Burst > 0 && o.Every > 0 }
+
+func (o *RateLimitOptions) summaryInterval() time.Duration {
+	if o.SummaryInterval <= 0 {
+		return DefaultSummaryInterval
+	}
+	return o.SummaryInterval
+}
+
+// limitKey identifies similar reports.
+type limitKey struct {
+	scope   string
+	message string
+	cause   string
+}
+
+func limitKeyFor(errCtx *errorContext) limitKey {
+	k := limitKey{scope: errCtx.Scope, message: errCtx.Message}
+	if errCtx.Error != nil {
+		k.cause = errors.Cause(errCtx.Error).Error()
+	}
+	return k
+}
+
+// bucket is the token bucket of a group of similar reports.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// suppressed is the number of reports suppressed since the last summary.
+	suppressed uint64
+	// latest is the most recently suppressed report, whose context is included in the
+	// summary.
+	latest *errorContext
+}
+
+// refill adds the tokens accumulated since the bucket was last used at t.
+func (b *bucket) refill(opts *RateLimitOptions, t time.Time) {
+	if elapsed := t.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(opts.Every)
+		if b.tokens > float64(opts.Burst) {
+			b.tokens = float64(opts.Burst)
+		}
+		b.last = t
+	}
+}
+
+// limiter rate limits similar reports. Summaries of suppressed reports are due once per
+// summary interval, and are sent by the worker when they are due or when the core is
+// synced.
+type limiter struct {
+	opts atomic.Pointer[RateLimitOptions]
+	// nextSummary is the time in Unix nanoseconds after which summaries are due.
+	nextSummary atomic.Int64
+
+	mu      sync.Mutex
+	buckets map[limitKey]*bucket
+}
+
+func newLimiter() *limiter {
+	l := &limiter{buckets: make(map[limitKey]*bucket)}
+	l.opts.Store(&RateLimitOptions{})
+	return l
+}
+
+// set replaces the options, discarding the state of rate limits and suppressed reports.
+func (l *limiter) set(opts RateLimitOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.opts.Store(&opts)
+	l.nextSummary.Store(time.Now().Add(opts.summaryInterval()).UnixNano())
+	l.buckets = make(map[limitKey]*bucket)
+}
+
+// allow reports whether errCtx may be reported at t, recording it as suppressed if not.
+func (l *limiter) allow(errCtx *errorContext, t time.Time) bool {
+	opts := l.opts.Load()
+	if !opts.enabled() {
+		return true
+	}
+	k := limitKeyFor(errCtx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[k]
+	if !ok {
+		if len(l.buckets) >= maxLimitKeys {
+			l.prune(opts, t)
+		}
+		if len(l.buckets) >= maxLimitKeys {
+			k = limitKey{}
+			b = l.buckets[k]
+		}
+		if b == nil {
+			b = &bucket{tokens: float64(opts.Burst), last: t}
+			l.buckets[k] = b
+		}
+	}
+
+	b.refill(opts, t)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	b.suppressed++
+	b.latest = errCtx
+	return false
+}
+
+// prune discards buckets that are full at t and have nothing to summarize, since they
+// are equivalent to new buckets. l.mu must be held.
+func (l *limiter) prune(opts *RateLimitOptions, t time.Time) {
+	for k, b := range l.buckets {
+		if b.suppressed > 0 {
+			continue
+		}
+		if b.refill(opts, t); b.tokens >= float64(opts.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// maybeSummarize returns summaries of suppressed reports if they are due at t.
+func (l *limiter) maybeSummarize(t time.Time) []*errorContext {
+	next := l.nextSummary.Load()
+	if t.UnixNano() < next {
+		return nil
+	}
+	if !l.nextSummary.CompareAndSwap(next, t.Add(l.opts.Load().summaryInterval()).UnixNano()) {
+		return nil // another goroutine is summarizing
+	}
+	return l.summarize()
+}
+
+// summarize returns summaries of all reports suppressed since the last summary. Each
+// summary is a message event with the context of the latest suppressed report, grouped
+// with other summaries of similar reports.
+func (l *limiter) summarize() []*errorContext {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	interval := l.opts.Load().summaryInterval()
+	var summaries []*errorContext
+	for k, b := range l.buckets {
+		if b.suppressed == 0 {
+			continue
+		}
+		base := b.latest.baseContext.clone()
+		base.Message = fmt.Sprintf("suppressed %d similar events in last %s: %s",
+			b.suppressed, interval, b.latest.Message)
+		base.Fingerprint = Fingerprint{"suppressed", k.scope, k.message, k.cause}
+		base.Fields = append(base.Fields, zap.Uint64("suppressed", b.suppressed))
+		if k.cause != "" {
+			base.Fields = append(base.Fields, zap.String("suppressedCause", k.cause))
+		}
+		summaries = append(summaries, &errorContext{baseContext: base})
+
+		b.suppressed = 0
+		b.latest = nil
+	}
+	return summaries
+}
@@ -0,0 +1,67 @@
+package sentrycore
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter()
+	newReport := func(scope, message string, err error) *errorContext {
+		return &errorContext{baseContext: &baseContext{Scope: scope, Message: message}, Error: err}
+	}
+	now := time.Now()
+
+	// Not limited by default
+	for i := 0; i < 10; i++ {
+		assert.True(t, l.allow(newReport("foo", "failed", nil), now))
+	}
+
+	l.set(RateLimitOptions{Burst: 2, Every: time.Minute})
+	e := errors.New("boom")
+	assert.True(t, l.allow(newReport("foo", "failed", e), now))
+	assert.True(t, l.allow(newReport("foo", "failed", fmt.Errorf("wrapped: %w", e)), now))
+	assert.False(t, l.allow(newReport("foo", "failed", e), now))
+	assert.False(t, l.allow(newReport("foo", "failed", e), now.Add(30*time.Second)))
+
+	// Other reports are not affected
+	assert.True(t, l.allow(newReport("foo", "failed", errors.New("other")), now))
+	assert.True(t, l.allow(newReport("foo", "other message", e), now))
+	assert.True(t, l.allow(newReport("bar", "failed", e), now))
+
+	// Tokens are refilled over time
+	assert.True(t, l.allow(newReport("foo", "failed", e), now.Add(time.Minute)))
+	assert.False(t, l.allow(newReport("foo", "failed", e), now.Add(time.Minute)))
+
+	summaries := l.summarize()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "suppressed 3 similar events in last 1m0s: failed", summaries[0].Message)
+	assert.Equal(t, Fingerprint{"suppressed", "foo", "failed", "boom"}, summaries[0].Fingerprint)
+	assert.Nil(t, summaries[0].Error)
+
+	// Summaries are only sent once
+	assert.Empty(t, l.summarize())
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	l := newLimiter()
+	l.set(RateLimitOptions{Burst: 1, Every: time.Hour})
+	now := time.Now()
+	for i := 0; i < maxLimitKeys; i++ {
+		l.allow(&errorContext{baseContext: &baseContext{Message: fmt.Sprint(i)}}, now)
+	}
+
+	// Further reports share a rate limit
+	assert.True(t, l.allow(&errorContext{baseContext: &baseContext{Message: "a"}}, now))
+	assert.False(t, l.allow(&errorContext{baseContext: &baseContext{Message: "b"}}, now))
+
+	// Full buckets are discarded to make room for new ones
+	later := now.Add(time.Hour)
+	assert.True(t, l.allow(&errorContext{baseContext: &baseContext{Message: "c"}}, later))
+	assert.True(t, l.allow(&errorContext{baseContext: &baseContext{Message: "d"}}, later))
+}
@@ -0,0 +1,44 @@
+package sentrycore
+
+import "sync/atomic"
+
+// Stats holds cumulative counts of reports handled by a Core, so that failures of error
+// reporting itself can be detected.
+type Stats struct {
+	// Queued is the number of reports queued to be sent.
+	Queued uint64
+	// Dropped is the number of reports dropped because the queue was full.
+	Dropped uint64
+	// RateLimited is the number of reports suppressed by the rate limit.
+	RateLimited uint64
+	// Sent is the number of events handed to the Sentry transport.
+	Sent uint64
+	// Discarded is the number of events discarded by the Sentry client, for example
+	// due to ClientOptions.SampleRate or BeforeSend, or because there is no client.
+	Discarded uint64
+	// Failed is the number of events that the Sentry transport may not have delivered,
+	// because they were still pending 5 seconds after being sent or when a flush timed
+	// out. The Sentry client does not surface other delivery failures.
+	Failed uint64
+}
+
+// stats holds the counters behind Stats.
+type stats struct {
+	queued      atomic.Uint64
+	dropped     atomic.Uint64
+	rateLimited atomic.Uint64
+	sent        atomic.Uint64
+	discarded   atomic.Uint64
+	failed      atomic.Uint64
+}
+
+func (s *stats) snapshot() Stats {
+	return Stats{
+		Queued:      s.queued.Load(),
+		Dropped:     s.dropped.Load(),
+		RateLimited: s.rateLimited.Load(),
+		Sent:        s.sent.Load(),
+		Discarded:   s.discarded.Load(),
+		Failed:      s.failed.Load(),
+	}
+}
@@ -18,6 +18,9 @@ const (
 	flushDelay = 500 * time.Millisecond
 	// sentryTimeout defines how much time Sentry has to send the events.
 	sentryTimeout = 5 * time.Second
+	// deliveryCheckTimeout defines how long the worker waits for Sentry to send the
+	// events when periodically checking if they were delivered.
+	deliveryCheckTimeout = 10 * time.Millisecond
 )
 
 // worker encapsulate the process of sending events to Sentry by asynchronously
@@ -33,6 +36,18 @@ type worker struct {
 	timeout chan struct{}
 	// done stops the worker from accepting new cores when written into.
 	done chan struct{}
+	// stats counts the events sent, and is shared with the cores.
+	stats *stats
+	// limits rate limits similar reports, and is shared with the cores. The worker sends
+	// summaries of suppressed reports when they are due.
+	limits *limiter
+	// pending holds when each event sent since the last flush was handed to Sentry,
+	// oldest first. It is only used by the consuming go routine, or by Flush once the
+	// consuming go routine has stopped.
+	pending []time.Time
+	// deliveryTimeout is how long Sentry has to send an event before it is counted as
+	// failed, when not flushing.
+	deliveryTimeout time.Duration
 }
 
 type sentryHub struct {
@@ -59,7 +74,11 @@ func (w *worker) consume() {
 		select {
 		case errC := <-w.C:
 			w.capture(errC)
-		case <-ticker.C:
+		case now := <-ticker.C:
+			for _, summary := range w.limits.maybeSummarize(now) {
+				w.queue(summary)
+			}
+			w.checkDelivered(now)
 			// We only check if we're closing periodically, to make sure we have
 			// consumed the last few events that were sent.
 			select {
@@ -83,11 +102,46 @@ func (w *worker) Flush() error {
 
 func (w *worker) flush() {
 	// Flush Sentry
-	w.hub.hub.Flush(sentryTimeout)
+	if !w.hub.hub.Flush(sentryTimeout) {
+		w.stats.failed.Add(uint64(len(w.pending)))
+	}
+	w.pending = w.pending[:0]
 	// Start accepting new errors again.
 	go w.consume()
 }
 
+// checkDelivered briefly flushes Sentry if there are pending events, and counts those
+// that have not been delivered within deliveryTimeout of being sent as failed. Events
+// sent more recently remain pending. This keeps the failed count current without
+// waiting for Flush.
+func (w *worker) checkDelivered(now time.Time) {
+	if w.hub.hub == nil || len(w.pending) == 0 {
+		return
+	}
+	if w.hub.hub.Flush(deliveryCheckTimeout) {
+		w.pending = w.pending[:0]
+		return
+	}
+	overdue := 0
+	for overdue < len(w.pending) && now.Sub(w.pending[overdue]) >= w.deliveryTimeout {
+		overdue++
+	}
+	if overdue > 0 {
+		w.stats.failed.Add(uint64(overdue))
+		w.pending = append(w.pending[:0], w.pending[overdue:]...)
+	}
+}
+
+// queue queues errC to be reported.
+func (w *worker) queue(errC *errorContext) {
+	select {
+	case w.C <- errC:
+		w.stats.queued.Add(1)
+	default: // if we can't queue, just drop the errors.
+		w.stats.dropped.Add(1)
+	}
+}
+
 func (w *worker) stop() {
 	w.done <- struct{}{}
 }
@@ -95,14 +149,26 @@ func (w *worker) stop() {
 // capture submits an ErrorContext to Sentry.
 func (w *worker) capture(errCtx *errorContext) {
 	if w.hub.hub == nil {
+		w.stats.discarded.Add(1)
 		return
 	}
-	// Extract a sentry event from the error itself. If the error is an errors.Error, it will
-	// include a stack trace and additional details.
-	event, extraDetails := errors.BuildSentryReport(errCtx.Error)
-	// Prepend the log message to the description, to increase visibility.
-	// This does not change how the errors are grouped.
-	event.Message = fmt.Sprintf("%s: %s\n--\n%s", errCtx.Scope, errCtx.Message, event.Message)
+	var (
+		event        *sentry.Event
+		extraDetails map[string]interface{}
+	)
+	if errCtx.Error == nil {
+		// Report the log message itself, which Sentry uses as the issue title and to
+		// group events.
+		event = sentry.NewEvent()
+		event.Message = fmt.Sprintf("[%s] %s", errCtx.Scope, errCtx.Message)
+	} else {
+		// Extract a sentry event from the error itself. If the error is an errors.Error, it will
+		// include a stack trace and additional details.
+		event, extraDetails = errors.BuildSentryReport(errCtx.Error)
+		// Prepend the log message to the description, to increase visibility.
+		// This does not change how the errors are grouped.
+		event.Message = fmt.Sprintf("%s: %s\n--\n%s", errCtx.Scope, errCtx.Message, event.Message)
+	}
 
 	if len(event.Exception) > 0 {
 		// Sentry uses the Type of the first exception as the issue title. By default,
@@ -168,19 +234,12 @@ func (w *worker) capture(errCtx *errorContext) {
 		f.AddTo(enc)
 	}
 
-	// Translate zapcore levels into Sentry levels.
-	var level sentry.Level
-	switch errCtx.Level {
-	case zapcore.DebugLevel:
-		level = sentry.LevelDebug
-	case zapcore.WarnLevel:
-		level = sentry.LevelWarning
-	case zapcore.ErrorLevel:
-		level = sentry.LevelError
-	case zapcore.FatalLevel, zapcore.PanicLevel:
-		level = sentry.LevelFatal
-	case zapcore.DPanicLevel:
-		level = sentry.LevelError
+	if fingerprint := errCtx.fingerprint(); fingerprint != nil {
+		event.Fingerprint = fingerprint
+	}
+
+	if len(errCtx.Breadcrumbs) > 0 {
+		event.Breadcrumbs = errCtx.Breadcrumbs
 	}
 
 	w.hub.Lock()
@@ -198,7 +257,31 @@ func (w *worker) capture(errCtx *errorContext) {
 			})
 		}
 		scope.SetTags(tags)
-		scope.SetLevel(level)
-		w.hub.hub.CaptureEvent(event)
+		scope.SetLevel(sentryLevel(errCtx.Level))
+		if w.hub.hub.CaptureEvent(event) != nil {
+			w.stats.sent.Add(1)
+			w.pending = append(w.pending, time.Now())
+		} else {
+			w.stats.discarded.Add(1)
+		}
 	})
 }
+
+// sentryLevel translates zapcore levels into Sentry levels.
+func sentryLevel(level zapcore.Level) sentry.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return sentry.LevelDebug
+	case zapcore.InfoLevel:
+		return sentry.LevelInfo
+	case zapcore.WarnLevel:
+		return sentry.LevelWarning
+	case zapcore.ErrorLevel:
+		return sentry.LevelError
+	case zapcore.FatalLevel, zapcore.PanicLevel:
+		return sentry.LevelFatal
+	case zapcore.DPanicLevel:
+		return sentry.LevelError
+	}
+	return ""
+}
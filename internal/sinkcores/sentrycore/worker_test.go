@@ -0,0 +1,59 @@
+package sentrycore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// undeliveredTransport never delivers events.
+type undeliveredTransport struct{ TransportMock }
+
+func (t *undeliveredTransport) Flush(time.Duration) bool { return false }
+
+func TestWorkerFailedWithoutFlush(t *testing.T) {
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: &undeliveredTransport{}})
+	require.NoError(t, err)
+	w := &worker{
+		hub:             sentryHub{hub: sentry.NewHub(client, sentry.NewScope())},
+		C:               make(chan *errorContext, bufferSize),
+		done:            make(chan struct{}),
+		stats:           &stats{},
+		limits:          newLimiter(),
+		deliveryTimeout: time.Millisecond,
+	}
+	w.start()
+	t.Cleanup(w.stop)
+
+	w.C <- &errorContext{baseContext: &baseContext{Scope: "foo", Message: "failed"}}
+
+	// Undelivered events are counted as failed without waiting for a flush
+	assert.Eventually(t, func() bool {
+		return w.stats.failed.Load() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(1), w.stats.sent.Load())
+}
+
+func TestWorkerCheckDelivered(t *testing.T) {
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: &undeliveredTransport{}})
+	require.NoError(t, err)
+	now := time.Now()
+	w := &worker{
+		hub:             sentryHub{hub: sentry.NewHub(client, sentry.NewScope())},
+		stats:           &stats{},
+		pending:         []time.Time{now.Add(-10 * time.Second), now.Add(-6 * time.Second), now.Add(-time.Second)},
+		deliveryTimeout: 5 * time.Second,
+	}
+
+	// Only events pending for longer than deliveryTimeout are counted as failed
+	w.checkDelivered(now)
+	assert.Equal(t, uint64(2), w.stats.failed.Load())
+	assert.Equal(t, []time.Time{now.Add(-time.Second)}, w.pending)
+
+	w.checkDelivered(now.Add(5 * time.Second))
+	assert.Equal(t, uint64(3), w.stats.failed.Load())
+	assert.Empty(t, w.pending)
+}
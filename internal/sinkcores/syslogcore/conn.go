@@ -0,0 +1,308 @@
+package syslogcore
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 5 * time.Second
+
+	// queueSize is the maximum number of messages waiting to be written. Messages
+	// written while the queue is full are dropped.
+	queueSize = 1024
+
+	// initialBackoff is the delay before the first reconnect after a failure.
+	initialBackoff = 500 * time.Millisecond
+	// maxBackoff is the maximum delay between reconnects.
+	maxBackoff = 30 * time.Second
+)
+
+// localAddresses are the paths where a local syslog daemon commonly listens, in order
+// of preference.
+var localAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Stats holds cumulative counts of messages that were not written by a Conn.
+type Stats struct {
+	// Dropped is the number of messages dropped because the queue was full.
+	Dropped uint64
+	// Failed is the number of messages that could not be written, because the
+	// connection failed and could not be re-established.
+	Failed uint64
+}
+
+// Conn is a connection to a syslog server that frames each message for the transport.
+// Messages are queued and written from a background goroutine, so that a slow or
+// unavailable server does not block logging. If a write fails, Conn reconnects with
+// exponential backoff, and messages that cannot be written meanwhile are counted as
+// failed.
+type Conn struct {
+	network string
+	address string
+
+	queue chan []byte
+	// flushC receives requests to write all queued messages, which are acknowledged by
+	// closing the received channel.
+	flushC chan chan struct{}
+
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+
+	closed atomic.Bool
+
+	// conn is only used by the background goroutine, but guarded by mux so that Close
+	// can abort a write in progress. Once aborted is set, no new connection is made.
+	mux     sync.Mutex
+	conn    net.Conn
+	aborted bool
+
+	// nextConnect is when the next reconnect may be attempted, and backoff is the
+	// delay before the reconnect after that. Both are only used by the background
+	// goroutine.
+	nextConnect time.Time
+	backoff     time.Duration
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// Dial connects to the syslog server at address over network, which must be one of
+// "unix", "unixgram", "udp" or "tcp". If network is empty, Dial connects to the local
+// syslog daemon.
+func Dial(network, address string) (*Conn, error) {
+	switch network {
+	case "":
+		if address != "" {
+			return nil, errors.New("network is required when address is set")
+		}
+	case "unix", "unixgram", "udp", "tcp":
+		if address == "" {
+			return nil, errors.New("address is required")
+		}
+	default:
+		return nil, errors.New("unsupported network " + strconv.Quote(network))
+	}
+
+	c := &Conn{
+		network: network,
+		address: address,
+		queue:   make(chan []byte, queueSize),
+		flushC:  make(chan chan struct{}),
+		backoff: initialBackoff,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	go c.run()
+	return c, nil
+}
+
+// connect replaces the current connection with a new one.
+func (c *Conn) connect() error {
+	c.disconnect()
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.aborted {
+		_ = conn.Close()
+		return net.ErrClosed
+	}
+	c.conn = conn
+	return nil
+}
+
+// dial connects to the server. It must only be called by one goroutine at a time.
+func (c *Conn) dial() (net.Conn, error) {
+	if c.network != "" {
+		return net.DialTimeout(c.network, c.address, dialTimeout)
+	}
+
+	// Try each local syslog address over both datagram and stream sockets, and remember
+	// the first one that works.
+	for _, address := range localAddresses {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, address, dialTimeout)
+			if err == nil {
+				c.network, c.address = network, address
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("unable to connect to local syslog daemon")
+}
+
+// stream indicates if the transport is a stream, in which case messages must be
+// framed.
+func (c *Conn) stream() bool {
+	return c.network == "tcp" || c.network == "unix"
+}
+
+// WriteMessage queues a single syslog message to be written. If the queue is full, the
+// message is dropped.
+func (c *Conn) WriteMessage(msg []byte) error {
+	if c.closed.Load() {
+		return net.ErrClosed
+	}
+
+	select {
+	case c.queue <- msg:
+	default:
+		c.dropped.Add(1)
+	}
+	return nil
+}
+
+// Stats returns the cumulative counts of messages that were not written.
+func (c *Conn) Stats() Stats {
+	return Stats{
+		Dropped: c.dropped.Load(),
+		Failed:  c.failed.Load(),
+	}
+}
+
+// Sync blocks until all queued messages have been written or dropped, or until the
+// write timeout elapses.
+func (c *Conn) Sync() error {
+	ack := make(chan struct{})
+	timeout := time.NewTimer(writeTimeout)
+	defer timeout.Stop()
+
+	select {
+	case c.flushC <- ack:
+	case <-c.stopped:
+		return nil
+	case <-timeout.C:
+		return errors.New("timed out waiting to flush")
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-timeout.C:
+		return errors.New("timed out waiting to flush")
+	}
+}
+
+// Close writes all queued messages and closes the connection. If this does not
+// complete within the write timeout, the remaining messages are dropped.
+func (c *Conn) Close() error {
+	c.closed.Store(true)
+	c.closeOnce.Do(func() { close(c.done) })
+
+	var err error
+	select {
+	case <-c.stopped:
+	case <-time.After(writeTimeout):
+		err = errors.New("timed out waiting to flush")
+	}
+
+	c.mux.Lock()
+	c.aborted = true
+	c.mux.Unlock()
+	c.disconnect()
+	<-c.stopped
+	return err
+}
+
+func (c *Conn) run() {
+	defer close(c.stopped)
+	for {
+		select {
+		case msg := <-c.queue:
+			c.send(msg)
+		case ack := <-c.flushC:
+			c.drain()
+			close(ack)
+		case <-c.done:
+			c.drain()
+			return
+		}
+	}
+}
+
+// drain writes all queued messages.
+func (c *Conn) drain() {
+	for {
+		select {
+		case msg := <-c.queue:
+			c.send(msg)
+		default:
+			return
+		}
+	}
+}
+
+// send writes msg, reconnecting and retrying once if the write fails. While reconnects
+// are backing off, msg is counted as failed without attempting to write it.
+func (c *Conn) send(msg []byte) {
+	if conn := c.current(); conn != nil {
+		if err := c.write(conn, msg); err == nil {
+			return
+		}
+	}
+
+	if time.Now().Before(c.nextConnect) {
+		c.disconnect()
+		c.failed.Add(1)
+		return
+	}
+	if err := c.connect(); err == nil {
+		if err := c.write(c.current(), msg); err == nil {
+			c.backoff = initialBackoff
+			return
+		}
+	}
+	c.disconnect()
+	c.scheduleConnect()
+	c.failed.Add(1)
+}
+
+// current returns the current connection, or nil if disconnected.
+func (c *Conn) current() net.Conn {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.conn
+}
+
+// disconnect closes the current connection, if any.
+func (c *Conn) disconnect() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func (c *Conn) scheduleConnect() {
+	c.nextConnect = time.Now().Add(c.backoff)
+	c.backoff *= 2
+	if c.backoff > maxBackoff {
+		c.backoff = maxBackoff
+	}
+}
+
+func (c *Conn) write(conn net.Conn, msg []byte) error {
+	if c.stream() {
+		// Octet-counting framing: https://datatracker.ietf.org/doc/html/rfc6587#section-3.4.1
+		framed := make([]byte, 0, len(msg)+8)
+		framed = strconv.AppendInt(framed, int64(len(msg)), 10)
+		framed = append(framed, ' ')
+		msg = append(framed, msg...)
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := conn.Write(msg)
+	return err
+}
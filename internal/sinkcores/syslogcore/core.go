@@ -0,0 +1,102 @@
+package syslogcore
+
+import (
+	"os"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/sinkcores/attributes"
+)
+
+// Options configures the syslog core.
+type Options struct {
+	// Facility is the syslog facility code to send messages with.
+	Facility int
+	// StructuredDataID is the SD-ID of the structured data element that attributes are
+	// encoded in.
+	StructuredDataID string
+}
+
+// core formats entries as RFC 5424 syslog messages and writes them to a Conn.
+//
+// The name of the Resource the root logger is annotated with is used as the APP-NAME,
+// and its InstanceID as the HOSTNAME. The scope of each entry is used as the MSGID, and
+// all attributes are encoded as params of a single structured data element.
+type core struct {
+	zapcore.LevelEnabler
+
+	conn *Conn
+	opts Options
+
+	hostname string
+	procID   string
+
+	// attrs are the attributes accumulated on this core.
+	attrs attributes.Set
+}
+
+var _ zapcore.Core = &core{}
+
+// NewCore instantiates a core that writes entries enabled by level to conn.
+func NewCore(level zapcore.LevelEnabler, conn *Conn, opts Options) zapcore.Core {
+	hostname, _ := os.Hostname()
+	return &core{
+		LevelEnabler: level,
+		conn:         conn,
+		opts:         opts,
+		hostname:     hostname,
+		procID:       strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.attrs = c.attrs.With(fields)
+	return &clone
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	attrs := c.attrs.With(fields)
+	hostname, appName := c.hostname, ""
+	if attrs.Resource != nil {
+		appName = attrs.Resource.Name
+		if attrs.Resource.InstanceID != "" {
+			hostname = attrs.Resource.InstanceID
+		}
+	}
+
+	params := toParams(attrs.Encode())
+	if ent.Caller.Defined {
+		params = append(params, param{name: "Caller", value: ent.Caller.TrimmedPath()})
+	}
+
+	msg := ent.Message
+	if ent.Stack != "" {
+		msg += "\n" + ent.Stack
+	}
+
+	m := message{
+		facility: c.opts.Facility,
+		severity: Severity(ent.Level),
+		time:     ent.Time,
+		hostname: hostname,
+		appName:  appName,
+		procID:   c.procID,
+		msgID:    ent.LoggerName,
+		sdID:     c.opts.StructuredDataID,
+		params:   params,
+		msg:      msg,
+	}
+	return c.conn.WriteMessage(m.appendTo(nil))
+}
+
+// Sync blocks until all queued messages have been written.
+func (c *core) Sync() error { return c.conn.Sync() }
@@ -0,0 +1,261 @@
+package syslogcore
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+func TestMessage(t *testing.T) {
+	m := message{
+		facility: 16,
+		severity: severityError,
+		time:     time.Date(2023, 1, 2, 3, 4, 5, 6000, time.UTC),
+		hostname: "host name",
+		appName:  "app",
+		procID:   "123",
+		msgID:    "foo.bar",
+		sdID:     "attributes@32473",
+		params: []param{
+			{name: "key", value: `a "quoted" \value]`},
+			{name: "bad=name", value: "v"},
+		},
+		msg: "hello",
+	}
+	assert.Equal(t,
+		`<131>1 2023-01-02T03:04:05.000006Z host_name app 123 foo.bar [attributes@32473 key="a \"quoted\" \\value\]" bad_name="v"] `+utf8BOM+"hello",
+		string(m.appendTo(nil)))
+
+	t.Run("nil values", func(t *testing.T) {
+		m := message{facility: 1, severity: severityInfo, time: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+		assert.Equal(t, `<14>1 2023-01-02T03:04:05.000000Z - - - - -`, string(m.appendTo(nil)))
+	})
+}
+
+func TestToParams(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range []zapcore.Field{
+		zap.String("s", "v"),
+		zap.Int("i", 1),
+		zap.Strings("arr", []string{"a", "b"}),
+		zap.Namespace("ns"),
+		zap.Bool("b", true),
+	} {
+		f.AddTo(enc)
+	}
+	assert.Equal(t, []param{
+		{name: "arr", value: `["a","b"]`},
+		{name: "i", value: "1"},
+		{name: "ns.b", value: "true"},
+		{name: "s", value: "v"},
+	}, toParams(enc.Fields))
+}
+
+func TestToParamsUnique(t *testing.T) {
+	long := strings.Repeat("a", maxSDName)
+	params := toParams(map[string]interface{}{
+		long + "1": "v1",
+		long + "2": "v2",
+		"a=b":      "v3",
+		"a_b":      "v4",
+	})
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.name
+	}
+	assert.Equal(t, []string{"a_b", "a_b~2", long, long[:maxSDName-2] + "~2"}, names)
+}
+
+func TestValidateStructuredDataID(t *testing.T) {
+	for _, id := range []string{"attributes@32473", "a@1.2.3"} {
+		assert.NoError(t, ValidateStructuredDataID(id), id)
+	}
+	for _, id := range []string{"", "attributes", "@32473", "attributes@", "attr ibutes@32473", "attributes@abc", "a@1@2", strings.Repeat("a", 30) + "@32473"} {
+		assert.Error(t, ValidateStructuredDataID(id), id)
+	}
+}
+
+func TestCore(t *testing.T) {
+	resource := zap.Object(otelfields.ResourceFieldKey, &encoders.ResourceEncoder{
+		Resource: otelfields.Resource{Name: "my-service", InstanceID: "instance-1"},
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = pc.Close() })
+
+		conn, err := Dial("udp", pc.LocalAddr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		logger := zap.New(NewCore(zapcore.InfoLevel, conn, Options{
+			Facility:         1,
+			StructuredDataID: "attributes@32473",
+		})).With(resource, otelfields.AttributesNamespace)
+		logger.Named("foo").Debug("dropped")
+		logger.Named("foo").Warn("hello", zap.String("key", "value"))
+
+		buf := make([]byte, 4096)
+		require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+
+		msg := string(buf[:n])
+		assert.True(t, strings.HasPrefix(msg, "<12>1 "), msg)
+		parts := strings.SplitN(msg, " ", 8)
+		assert.Equal(t, []string{"instance-1", "my-service", strconv.Itoa(os.Getpid()), "foo"}, parts[2:6])
+		assert.Equal(t, `[attributes@32473 key="value"] `+utf8BOM+"hello", strings.Join(parts[6:], " "))
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = lis.Close() })
+		received := make(chan string, 2)
+		go func() {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			r := bufio.NewReader(c)
+			for {
+				// Read octet-counted frames
+				length, err := r.ReadString(' ')
+				if err != nil {
+					return
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(length))
+				frame := make([]byte, n)
+				if _, err := io.ReadFull(r, frame); err != nil {
+					return
+				}
+				received <- string(frame)
+			}
+		}()
+
+		conn, err := Dial("tcp", lis.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		logger := zap.New(NewCore(zapcore.InfoLevel, conn, Options{Facility: 1})).With(resource)
+		logger.Info("first")
+		logger.Error("second")
+
+		for _, want := range []string{"<14>1 ", "<11>1 "} {
+			select {
+			case msg := <-received:
+				assert.True(t, strings.HasPrefix(msg, want), msg)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for message")
+			}
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = pc.Close() })
+
+		conn, err := Dial("udp", pc.LocalAddr().String())
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+		assert.ErrorIs(t, conn.WriteMessage([]byte("hello")), net.ErrClosed)
+	})
+}
+
+func TestConn(t *testing.T) {
+	t.Run("drops messages when the queue is full", func(t *testing.T) {
+		// No background goroutine is started, so the queue is never drained
+		c := &Conn{queue: make(chan []byte, 1)}
+		require.NoError(t, c.WriteMessage([]byte("first")))
+		require.NoError(t, c.WriteMessage([]byte("second")))
+		assert.Equal(t, Stats{Dropped: 1}, c.Stats())
+	})
+
+	t.Run("reconnects", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := lis.Addr().String()
+		received := make(chan string, 100)
+		serve := func(lis net.Listener) {
+			for {
+				c, err := lis.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer c.Close()
+					r := bufio.NewReader(c)
+					for {
+						if _, err := r.ReadString(' '); err != nil {
+							return
+						}
+						msg, _ := r.ReadString('\n')
+						received <- strings.TrimSpace(msg)
+					}
+				}()
+			}
+		}
+		go serve(lis)
+
+		conn, err := Dial("tcp", addr)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		require.NoError(t, conn.WriteMessage([]byte("first\n")))
+		require.NoError(t, conn.Sync())
+		assert.Equal(t, "first", <-received)
+
+		// Writes fail while the server is down
+		require.NoError(t, lis.Close())
+		conn.disconnect()
+		require.NoError(t, conn.WriteMessage([]byte("lost\n")))
+		require.NoError(t, conn.Sync())
+		assert.Equal(t, Stats{Failed: 1}, conn.Stats())
+
+		// Once the server is back, the connection is re-established after the backoff
+		lis, err = net.Listen("tcp", addr)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = lis.Close() })
+		go serve(lis)
+
+		require.Eventually(t, func() bool {
+			_ = conn.WriteMessage([]byte("second\n"))
+			_ = conn.Sync()
+			for {
+				select {
+				case msg := <-received:
+					if msg == "second" {
+						return true
+					}
+				default:
+					return false
+				}
+			}
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+}
+
+func TestDial(t *testing.T) {
+	_, err := Dial("udp", "")
+	assert.Error(t, err)
+	_, err = Dial("", "localhost:514")
+	assert.Error(t, err)
+	_, err = Dial("http", "localhost:514")
+	assert.Error(t, err)
+}
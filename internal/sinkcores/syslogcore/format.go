@@ -0,0 +1,231 @@
+package syslogcore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/sinkcores/attributes"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc5424#section-6
+const (
+	version        = "1"
+	nilValue       = "-"
+	timestampFmt   = "2006-01-02T15:04:05.000000Z07:00"
+	maxHostname    = 255
+	maxAppName     = 48
+	maxProcID      = 128
+	maxMsgID       = 32
+	maxSDName      = 32
+	utf8BOM        = "\xEF\xBB\xBF"
+	sdValueEscapes = `"\]`
+)
+
+// Severity values: https://datatracker.ietf.org/doc/html/rfc5424#section-6.2.1
+const (
+	severityEmergency = 0
+	severityAlert     = 1
+	severityCritical  = 2
+	severityError     = 3
+	severityWarning   = 4
+	severityInfo      = 6
+	severityDebug     = 7
+)
+
+// Severity returns the syslog severity corresponding to level. It is also used for the
+// PRIORITY of journald entries.
+func Severity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return severityDebug
+	case zapcore.InfoLevel:
+		return severityInfo
+	case zapcore.WarnLevel:
+		return severityWarning
+	case zapcore.ErrorLevel:
+		return severityError
+	case zapcore.DPanicLevel:
+		return severityCritical
+	case zapcore.PanicLevel:
+		return severityAlert
+	case zapcore.FatalLevel:
+		return severityEmergency
+	}
+	return severityInfo
+}
+
+// message is a syslog message in the format described by RFC 5424.
+type message struct {
+	facility int
+	severity int
+	time     time.Time
+	hostname string
+	appName  string
+	procID   string
+	msgID    string
+
+	// sdID is the SD-ID of the structured data element that holds params.
+	sdID   string
+	params []param
+
+	msg string
+}
+
+type param struct{ name, value string }
+
+// appendTo appends the formatted message to buf.
+func (m *message) appendTo(buf []byte) []byte {
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(m.facility*8+m.severity), 10)
+	buf = append(buf, '>')
+	buf = append(buf, version...)
+	buf = append(buf, ' ')
+	buf = m.time.AppendFormat(buf, timestampFmt)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.hostname, maxHostname)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.appName, maxAppName)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.procID, maxProcID)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.msgID, maxMsgID)
+	buf = append(buf, ' ')
+
+	if len(m.params) == 0 {
+		buf = append(buf, nilValue...)
+	} else {
+		buf = append(buf, '[')
+		buf = appendSDName(buf, m.sdID)
+		for _, p := range m.params {
+			buf = append(buf, ' ')
+			buf = appendSDName(buf, p.name)
+			buf = append(buf, '=', '"')
+			buf = appendSDValue(buf, p.value)
+			buf = append(buf, '"')
+		}
+		buf = append(buf, ']')
+	}
+
+	if m.msg != "" {
+		buf = append(buf, ' ')
+		buf = append(buf, utf8BOM...)
+		buf = append(buf, m.msg...)
+	}
+	return buf
+}
+
+// appendHeaderField appends a header field, which must consist of printable US-ASCII
+// characters. Other characters are replaced with '_'.
+func appendHeaderField(buf []byte, v string, maxLen int) []byte {
+	if v == "" {
+		return append(buf, nilValue...)
+	}
+	if len(v) > maxLen {
+		v = v[:maxLen]
+	}
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c >= 33 && c <= 126 {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
+
+// ValidateStructuredDataID returns an error if id is not a valid SD-ID for custom
+// structured data, i.e. a name followed by '@' and a private enterprise number, such
+// as "attributes@12345". See RFC 5424 section 7.2.2.
+func ValidateStructuredDataID(id string) error {
+	if len(id) > maxSDName {
+		return fmt.Errorf("structured data ID %q is longer than %d characters", id, maxSDName)
+	}
+	name, pen, ok := strings.Cut(id, "@")
+	if !ok || name == "" || pen == "" {
+		return fmt.Errorf("structured data ID %q must be of the form name@enterprise-number", id)
+	}
+	if !validSDName(name) || strings.Contains(pen, "@") {
+		return fmt.Errorf("structured data ID %q contains invalid characters", id)
+	}
+	for _, part := range strings.Split(pen, ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return fmt.Errorf("structured data ID %q has an invalid enterprise number", id)
+		}
+	}
+	return nil
+}
+
+// validSDName reports whether v consists of printable US-ASCII characters except '=',
+// ' ', ']' and '"'.
+func validSDName(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if !validSDNameChar(v[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func validSDNameChar(c byte) bool {
+	return c >= 33 && c <= 126 && c != '=' && c != ']' && c != '"'
+}
+
+// sdName returns v as a valid SD-ID or PARAM-NAME, replacing invalid characters with
+// '_' and truncating it to maxLen.
+func sdName(v string, maxLen int) string {
+	if v == "" {
+		return "_"
+	}
+	if len(v) > maxLen {
+		v = v[:maxLen]
+	}
+	b := []byte(v)
+	for i, c := range b {
+		if !validSDNameChar(c) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// appendSDName appends an SD-ID or PARAM-NAME, replacing invalid characters with '_'.
+func appendSDName(buf []byte, v string) []byte {
+	return append(buf, sdName(v, maxSDName)...)
+}
+
+// appendSDValue appends a PARAM-VALUE, escaping '"', '\' and ']'.
+func appendSDValue(buf []byte, v string) []byte {
+	for i := 0; i < len(v); i++ {
+		if strings.IndexByte(sdValueEscapes, v[i]) >= 0 {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, v[i])
+	}
+	return buf
+}
+
+// toParams flattens fields rendered by zapcore.MapObjectEncoder into structured data
+// params, joining the keys of nested objects with '.'. Keys are made valid PARAM-NAMEs,
+// and if that makes a name collide with a previous one, such as when keys are truncated
+// to 32 characters, the name is suffixed with '~' and a number to keep it unique.
+func toParams(fields map[string]interface{}) []param {
+	var params []param
+	names := make(map[string]struct{})
+	attributes.Flatten(fields, ".", func(key string, value interface{}) {
+		name := sdName(key, maxSDName)
+		for i := 2; ; i++ {
+			if _, exists := names[name]; !exists {
+				break
+			}
+			suffix := "~" + strconv.Itoa(i)
+			name = sdName(key, maxSDName-len(suffix)) + suffix
+		}
+		names[name] = struct{}{}
+		params = append(params, param{name: name, value: attributes.String(value)})
+	})
+	return params
+}
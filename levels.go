@@ -44,3 +44,19 @@ func (l Level) Parse() zapcore.Level {
 	// Quietly fall back to warn
 	return zapcore.WarnLevel
 }
+
+// levelFromZap returns the Level corresponding to the given zapcore.Level, as parsed by
+// Level.Parse.
+func levelFromZap(l zapcore.Level) Level {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return LevelDebug
+	case l == zapcore.InfoLevel:
+		return LevelInfo
+	case l == zapcore.WarnLevel:
+		return LevelWarn
+	case l < zapcore.FatalLevel:
+		return LevelError
+	}
+	return LevelNone
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"fmt"
 
 	"go.uber.org/zap"
@@ -77,6 +78,24 @@ type Logger interface {
 	IncreaseLevel(scope string, description string, level Level) Logger
 }
 
+// EnabledLogger is an optional interface for Loggers that can report whether entries
+// at a level would be logged, for example to skip building expensive fields. AddCallerSkip
+// and other methods returning a Logger should also return an EnabledLogger.
+type EnabledLogger interface {
+	Logger
+
+	Enabled(ctx context.Context, level Level) bool
+}
+
+// Enabled reports whether logger logs entries at level, or at least entries at level in
+// some scope. If logger does not implement EnabledLogger, Enabled returns true.
+func Enabled(ctx context.Context, logger Logger, level Level) bool {
+	if el, ok := logger.(EnabledLogger); ok {
+		return el.Enabled(ctx, level)
+	}
+	return true
+}
+
 // Scoped returns the global logger and sets it up with the given scope and OpenTelemetry
 // compliant implementation. Instead of using this everywhere a log is needed, callers
 // should hold a reference to the Logger and pass it in to places that need to log.
@@ -135,7 +154,7 @@ type zapAdapter struct {
 	fromPackageScoped bool
 }
 
-var _ Logger = &zapAdapter{}
+var _ EnabledLogger = &zapAdapter{}
 
 func (z *zapAdapter) Scoped(scope string) Logger {
 	var newFullScope string
@@ -144,6 +163,7 @@ func (z *zapAdapter) Scoped(scope string) Logger {
 	} else {
 		newFullScope = createScope(z.fullScope, scope)
 	}
+	recordScope(newFullScope)
 	return &zapAdapter{
 		// name -> scope in OT
 		Logger:     z.Logger.Named(scope),
@@ -210,6 +230,10 @@ func (z *zapAdapter) IncreaseLevel(scope string, description string, level Level
 	}
 }
 
+func (z *zapAdapter) Enabled(_ context.Context, level Level) bool {
+	return z.Core().Enabled(level.Parse())
+}
+
 // WithCore is an internal API used to allow packages like logtest to hook into
 // underlying zap logger's core.
 //
@@ -1,6 +1,7 @@
 package log_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -90,3 +91,15 @@ func TestWithTrace(t *testing.T) {
 	assert.Equal(t, "1", logs[1].Fields["TraceId"])
 	assert.Equal(t, "2", logs[2].Fields["TraceId"])
 }
+
+func TestEnabled(t *testing.T) {
+	logger, _ := newTestLogger(t)
+	ctx := context.Background()
+	assert.True(t, log.Enabled(ctx, logger, log.LevelDebug))
+
+	logger = logger.IncreaseLevel("foo", "", log.LevelWarn)
+	assert.False(t, log.Enabled(ctx, logger, log.LevelInfo))
+	assert.True(t, log.Enabled(ctx, logger, log.LevelWarn))
+
+	assert.False(t, log.Enabled(ctx, log.NoOp(), log.LevelError))
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+// TraceFlags represents W3C trace flags.
+//
+// https://opentelemetry.io/docs/reference/specification/logs/data-model/#field-traceflags
+type TraceFlags = otelfields.TraceFlags
+
+// TraceFlagsSampled indicates that the caller may have recorded trace data.
+const TraceFlagsSampled = otelfields.TraceFlagsSampled
+
+// TraceContextFromSpanContext derives a TraceContext from an OpenTelemetry SpanContext.
+// If the SpanContext is not valid, the zero-value TraceContext is returned, which
+// no-ops when provided to Logger.WithTrace.
+func TraceContextFromSpanContext(sc trace.SpanContext) TraceContext {
+	if !sc.IsValid() {
+		return TraceContext{}
+	}
+	return TraceContext{
+		TraceID:    sc.TraceID().String(),
+		SpanID:     sc.SpanID().String(),
+		TraceFlags: TraceFlags(sc.TraceFlags()),
+	}
+}
+
+// traceContextFromSpan derives a TraceContext from the OpenTelemetry span active in
+// ctx, if there is one.
+func traceContextFromSpan(ctx context.Context) (TraceContext, bool) {
+	tc := TraceContextFromSpanContext(trace.SpanContextFromContext(ctx))
+	return tc, tc.TraceID != ""
+}
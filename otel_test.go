@@ -0,0 +1,79 @@
+package log_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sourcegraph/log"
+)
+
+func newSpanContext(t *testing.T, flags trace.TraceFlags) trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	assert.NoError(t, err)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+	})
+}
+
+func TestTraceContextFromSpanContext(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		assert.Equal(t, log.TraceContext{}, log.TraceContextFromSpanContext(trace.SpanContext{}))
+	})
+
+	t.Run("sampled", func(t *testing.T) {
+		tc := log.TraceContextFromSpanContext(newSpanContext(t, trace.FlagsSampled))
+		assert.Equal(t, log.TraceContext{
+			TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:     "00f067aa0ba902b7",
+			TraceFlags: log.TraceFlagsSampled,
+		}, tc)
+		assert.True(t, tc.TraceFlags.IsSampled())
+	})
+
+	t.Run("not sampled", func(t *testing.T) {
+		tc := log.TraceContextFromSpanContext(newSpanContext(t, 0))
+		assert.False(t, tc.TraceFlags.IsSampled())
+	})
+}
+
+func TestTraceContextFromContext(t *testing.T) {
+	t.Run("no trace", func(t *testing.T) {
+		_, ok := log.TraceContextFromContext(context.Background())
+		assert.False(t, ok)
+	})
+
+	t.Run("active span", func(t *testing.T) {
+		ctx := trace.ContextWithSpanContext(context.Background(), newSpanContext(t, trace.FlagsSampled))
+		tc, ok := log.TraceContextFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	})
+
+	t.Run("explicit trace takes precedence", func(t *testing.T) {
+		ctx := trace.ContextWithSpanContext(context.Background(), newSpanContext(t, trace.FlagsSampled))
+		ctx = log.WithTraceContext(ctx, log.TraceContext{TraceID: "explicit"})
+		tc, ok := log.TraceContextFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "explicit", tc.TraceID)
+	})
+}
+
+func TestLoggerCtxSpan(t *testing.T) {
+	logger, exportLogs := newTestLogger(t)
+
+	ctx := trace.ContextWithSpanContext(context.Background(), newSpanContext(t, trace.FlagsSampled))
+	log.InfoCtx(ctx, logger, "with span")
+
+	logs := exportLogs()
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", logs[0].Fields["TraceId"])
+	assert.Equal(t, "00f067aa0ba902b7", logs[0].Fields["SpanId"])
+	assert.Equal(t, "01", logs[0].Fields["TraceFlags"])
+}
@@ -0,0 +1,240 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log"
+)
+
+// record is a log record pending export, along with the resource and scope it belongs
+// to.
+type record struct {
+	resource log.Resource
+	scope    string
+	log      *logspb.LogRecord
+}
+
+// core converts log entries into OTLP log records and queues them for export.
+type core struct {
+	zapcore.LevelEnabler
+
+	exporter *exporter
+
+	resource log.Resource
+	trace    log.TraceContext
+	// fields are the attributes accumulated on this core, excluding the resource and
+	// trace context.
+	fields []zapcore.Field
+}
+
+var _ zapcore.Core = &core{}
+
+func newCore(level zapcore.LevelEnabler, exporter *exporter) *core {
+	return &core{
+		LevelEnabler: level,
+		exporter:     exporter,
+	}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, len(c.fields), len(c.fields)+len(fields))
+	copy(clone.fields, c.fields)
+	clone.fields = clone.addFields(clone.fields, fields)
+	return &clone
+}
+
+// addFields appends fields to dst, extracting the resource and trace context from
+// fields into c.
+func (c *core) addFields(dst []zapcore.Field, fields []zapcore.Field) []zapcore.Field {
+	for _, f := range fields {
+		if r, ok := log.ResourceFromField(f); ok {
+			c.resource = r
+			continue
+		}
+		if t, ok := log.TraceContextFromField(f); ok {
+			c.trace = t
+			continue
+		}
+		if log.IsAttributesNamespace(f) {
+			// All fields are OTLP attributes already.
+			continue
+		}
+		dst = append(dst, f)
+	}
+	return dst
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// Copy the core to capture any resource and trace context in fields.
+	wc := *c
+	all := make([]zapcore.Field, len(c.fields), len(c.fields)+len(fields))
+	copy(all, c.fields)
+	all = wc.addFields(all, fields)
+
+	lr := &logspb.LogRecord{
+		TimeUnixNano:         uint64(ent.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       toSeverityNumber(ent.Level),
+		SeverityText:         ent.Level.CapitalString(),
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: ent.Message}},
+		Attributes:           toAttributes(ent, all),
+	}
+	if id, err := hex.DecodeString(wc.trace.TraceID); err == nil && len(id) == 16 {
+		lr.TraceId = id
+	}
+	if id, err := hex.DecodeString(wc.trace.SpanID); err == nil && len(id) == 8 {
+		lr.SpanId = id
+	}
+	lr.Flags = uint32(wc.trace.TraceFlags)
+
+	wc.exporter.enqueue(&record{
+		resource: wc.resource,
+		scope:    ent.LoggerName,
+		log:      lr,
+	})
+	return nil
+}
+
+// Sync flushes all queued records.
+func (c *core) Sync() error {
+	return c.exporter.flush()
+}
+
+func toSeverityNumber(level zapcore.Level) logspb.SeverityNumber {
+	switch level {
+	case zapcore.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case zapcore.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case zapcore.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case zapcore.ErrorLevel, zapcore.DPanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case zapcore.PanicLevel, zapcore.FatalLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	}
+	return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+}
+
+// toAttributes renders fields and the caller of the entry as OTLP attributes.
+func toAttributes(ent zapcore.Entry, fields []zapcore.Field) []*commonpb.KeyValue {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	attrs := toKeyValues(enc.Fields)
+
+	// https://opentelemetry.io/docs/specs/semconv/attributes-registry/code/
+	if ent.Caller.Defined {
+		attrs = append(attrs,
+			stringKeyValue("code.filepath", ent.Caller.File),
+			&commonpb.KeyValue{Key: "code.lineno", Value: toAnyValue(ent.Caller.Line)})
+		if ent.Caller.Function != "" {
+			attrs = append(attrs, stringKeyValue("code.function", ent.Caller.Function))
+		}
+	}
+	// https://opentelemetry.io/docs/specs/semconv/attributes-registry/exception/
+	if ent.Stack != "" {
+		attrs = append(attrs, stringKeyValue("exception.stacktrace", ent.Stack))
+	}
+	return attrs
+}
+
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func toKeyValues(m map[string]interface{}) []*commonpb.KeyValue {
+	kvs := make([]*commonpb.KeyValue, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		kvs = append(kvs, &commonpb.KeyValue{Key: k, Value: toAnyValue(m[k])})
+	}
+	return kvs
+}
+
+// toAnyValue converts values rendered by zapcore.MapObjectEncoder into OTLP values.
+func toAnyValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint:
+		return intValue(int64(v))
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case uint64:
+		return intValue(int64(v))
+	case uintptr:
+		return intValue(int64(v))
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case time.Duration:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.String()}}
+	case fmt.Stringer:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.String()}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+			KvlistValue: &commonpb.KeyValueList{Values: toKeyValues(v)},
+		}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i, e := range v {
+			values[i] = toAnyValue(e)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+			ArrayValue: &commonpb.ArrayValue{Values: values},
+		}}
+	}
+
+	// Reflected values - render as JSON, like zap would.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("%+v", v)}}
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: string(b)}}
+}
+
+func intValue(v int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+}
@@ -0,0 +1,5 @@
+// otlp provides a sink that exports log entries to an OpenTelemetry collector as OTLP
+// LogRecords, over gRPC or HTTP/protobuf.
+//
+// https://opentelemetry.io/docs/specs/otlp/
+package otlp
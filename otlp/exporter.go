@@ -0,0 +1,240 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log"
+)
+
+const (
+	// initialBackoff is the delay before the first retry of a failed export.
+	initialBackoff = 500 * time.Millisecond
+	// maxBackoff is the maximum delay between retries of a failed export.
+	maxBackoff = 30 * time.Second
+)
+
+// exporter batches queued records and exports them with a client in a background
+// goroutine.
+type exporter struct {
+	client client
+	opts   Options
+
+	// queue holds records pending export. Records are dropped when it is full.
+	queue chan *record
+	// flushC receives requests to export all queued records, which are acknowledged
+	// by closing the received channel.
+	flushC chan chan struct{}
+
+	// ctx is cancelled when shutdown times out, to abort any retries in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	shutdownOnce sync.Once
+	// done is closed to stop the exporter.
+	done chan struct{}
+	// stopped is closed when the exporter has stopped.
+	stopped chan struct{}
+}
+
+func newExporter(c client, opts Options) *exporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &exporter{
+		client:  c,
+		opts:    opts,
+		queue:   make(chan *record, opts.QueueSize),
+		flushC:  make(chan chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+func (e *exporter) start() { go e.run() }
+
+// enqueue queues r for export, dropping it if the queue is full.
+func (e *exporter) enqueue(r *record) {
+	select {
+	case e.queue <- r:
+	default: // if we can't queue, just drop the record.
+	}
+}
+
+// flush blocks until all queued records have been exported, or until ExportTimeout
+// elapses.
+func (e *exporter) flush() error {
+	ack := make(chan struct{})
+	timeout := time.NewTimer(e.opts.ExportTimeout)
+	defer timeout.Stop()
+
+	select {
+	case e.flushC <- ack:
+	case <-e.stopped:
+		return nil
+	case <-timeout.C:
+		return errors.New("OTLPSink: timed out waiting to flush")
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-timeout.C:
+		return errors.New("OTLPSink: timed out waiting to flush")
+	}
+}
+
+// shutdown exports all queued records and stops the exporter. If ctx is done before
+// that completes, any export in progress is aborted.
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.shutdownOnce.Do(func() { close(e.done) })
+
+	var err error
+	select {
+	case <-e.stopped:
+	case <-ctx.Done():
+		e.cancel()
+		<-e.stopped
+		err = ctx.Err()
+	}
+	e.cancel()
+	return errors.Join(err, e.client.close())
+}
+
+func (e *exporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.opts.BatchTimeout)
+	defer ticker.Stop()
+
+	var batch []*record
+	exportBatch := func() {
+		if len(batch) > 0 {
+			e.export(batch)
+			batch = nil
+		}
+	}
+	// drain exports everything that is currently queued.
+	drain := func() {
+		for {
+			select {
+			case r := <-e.queue:
+				batch = append(batch, r)
+				if len(batch) >= e.opts.BatchSize {
+					exportBatch()
+				}
+			default:
+				exportBatch()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case r := <-e.queue:
+			batch = append(batch, r)
+			if len(batch) >= e.opts.BatchSize {
+				exportBatch()
+			}
+		case <-ticker.C:
+			exportBatch()
+		case ack := <-e.flushC:
+			drain()
+			close(ack)
+		case <-e.done:
+			drain()
+			return
+		}
+	}
+}
+
+// export sends the batch with the client, retrying with exponential backoff until
+// MaxElapsedTime has elapsed. Batches that cannot be exported are dropped.
+func (e *exporter) export(batch []*record) {
+	req := buildRequest(batch)
+
+	deadline := time.Now().Add(e.opts.MaxElapsedTime)
+	backoff := initialBackoff
+	for {
+		ctx, cancel := context.WithTimeout(e.ctx, e.opts.ExportTimeout)
+		err := e.client.export(ctx, req)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			return // permanent failure
+		}
+		wait := backoff
+		if retryable.after > 0 {
+			wait = retryable.after
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-e.ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// buildRequest groups records by resource and scope, preserving the order in which
+// they were logged.
+func buildRequest(batch []*record) *collogspb.ExportLogsServiceRequest {
+	req := &collogspb.ExportLogsServiceRequest{}
+
+	resourceLogs := make(map[log.Resource]*logspb.ResourceLogs)
+	scopeLogs := make(map[log.Resource]map[string]*logspb.ScopeLogs)
+	for _, r := range batch {
+		rl, ok := resourceLogs[r.resource]
+		if !ok {
+			rl = &logspb.ResourceLogs{Resource: toResource(r.resource)}
+			resourceLogs[r.resource] = rl
+			scopeLogs[r.resource] = make(map[string]*logspb.ScopeLogs)
+			req.ResourceLogs = append(req.ResourceLogs, rl)
+		}
+		sl, ok := scopeLogs[r.resource][r.scope]
+		if !ok {
+			sl = &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: r.scope}}
+			scopeLogs[r.resource][r.scope] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		sl.LogRecords = append(sl.LogRecords, r.log)
+	}
+	return req
+}
+
+// toResource renders the resource with the same attributes as log output.
+func toResource(r log.Resource) *resourcepb.Resource {
+	enc := zapcore.NewMapObjectEncoder()
+	_ = log.MarshalResource(r, enc)
+	return &resourcepb.Resource{Attributes: toKeyValues(enc.Fields)}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
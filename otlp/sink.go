@@ -0,0 +1,150 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log"
+)
+
+// Protocol is the transport used to export log records.
+type Protocol string
+
+const (
+	// ProtocolGRPC exports log records with the OTLP/gRPC protocol.
+	ProtocolGRPC Protocol = "grpc"
+	// ProtocolHTTPProtobuf exports log records with the OTLP/HTTP protocol, using
+	// binary-encoded protobuf payloads.
+	ProtocolHTTPProtobuf Protocol = "http/protobuf"
+)
+
+const (
+	defaultBatchSize      = 512
+	defaultBatchTimeout   = time.Second
+	defaultQueueSize      = 2048
+	defaultExportTimeout  = 10 * time.Second
+	defaultMaxElapsedTime = time.Minute
+)
+
+// Options configures the OTLP sink.
+type Options struct {
+	// Endpoint is the collector endpoint to export to. For ProtocolGRPC, this is a host
+	// and port, e.g. "localhost:4317". For ProtocolHTTPProtobuf, this is the full URL,
+	// e.g. "http://localhost:4318/v1/logs". Required.
+	Endpoint string
+	// Protocol is the transport to use. Defaults to ProtocolGRPC.
+	Protocol Protocol
+	// Insecure disables TLS for ProtocolGRPC. For ProtocolHTTPProtobuf, TLS is used
+	// based on the scheme of the Endpoint.
+	Insecure bool
+	// Headers are sent with each export request, e.g. for authentication.
+	Headers map[string]string
+
+	// Level is the minimum level of entries to export. Defaults to the level configured
+	// with log.EnvLogLevel.
+	Level log.Level
+
+	// BatchSize is the maximum number of log records to export in a single request.
+	// Defaults to 512.
+	BatchSize int
+	// BatchTimeout is the maximum duration to wait before exporting a batch that is not
+	// full. Defaults to 1 second.
+	BatchTimeout time.Duration
+	// QueueSize is the maximum number of log records to buffer for export. Records that
+	// are logged while the queue is full are dropped. Defaults to 2048.
+	QueueSize int
+	// ExportTimeout is the timeout for each export request. Defaults to 10 seconds.
+	ExportTimeout time.Duration
+	// MaxElapsedTime is the maximum duration to spend retrying the export of a batch
+	// before dropping it. Defaults to 1 minute.
+	MaxElapsedTime time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.Protocol == "" {
+		o.Protocol = ProtocolGRPC
+	}
+	if o.Level == "" {
+		o.Level = log.Level(os.Getenv(log.EnvLogLevel))
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultBatchSize
+	}
+	if o.BatchTimeout <= 0 {
+		o.BatchTimeout = defaultBatchTimeout
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultQueueSize
+	}
+	if o.ExportTimeout <= 0 {
+		o.ExportTimeout = defaultExportTimeout
+	}
+	if o.MaxElapsedTime <= 0 {
+		o.MaxElapsedTime = defaultMaxElapsedTime
+	}
+}
+
+// NewSink instantiates an OTLP sink to provide to `log.Init` with the given options.
+//
+// Log entries are exported asynchronously in batches, and failed exports are retried
+// with exponential backoff. If entries are logged faster than they can be exported,
+// entries are dropped. The Sync callback returned by `log.Init` flushes all buffered
+// entries, and the Close callback also shuts down the exporter.
+func NewSink(opts Options) log.Sink {
+	opts.setDefaults()
+	return log.NewCustomSink(&sink{opts: opts})
+}
+
+type sink struct {
+	opts     Options
+	exporter *exporter
+}
+
+var _ log.CustomSink = &sink{}
+var _ io.Closer = &sink{}
+
+func (s *sink) Name() string { return "OTLPSink" }
+
+func (s *sink) Build() (zapcore.Core, error) {
+	if s.opts.Endpoint == "" {
+		return nil, errors.New("OTLPSink: Endpoint is required")
+	}
+
+	var (
+		c   client
+		err error
+	)
+	switch s.opts.Protocol {
+	case ProtocolGRPC:
+		c, err = newGRPCClient(s.opts)
+	case ProtocolHTTPProtobuf:
+		c, err = newHTTPClient(s.opts)
+	default:
+		err = errors.New("OTLPSink: unknown protocol " + string(s.opts.Protocol))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.exporter = newExporter(c, s.opts)
+	s.exporter.start()
+	return newCore(s.opts.Level.Parse(), s.exporter), nil
+}
+
+// Update is a no-op because the OTLP sink cannot be changed live.
+func (s *sink) Update(log.SinksConfig) error { return nil }
+
+// Close flushes all buffered entries and shuts down the exporter.
+func (s *sink) Close() error {
+	if s.exporter == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.ExportTimeout)
+	defer cancel()
+	return s.exporter.shutdown(ctx)
+}
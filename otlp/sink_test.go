@@ -0,0 +1,226 @@
+package otlp
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sourcegraph/log"
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+// collector records export requests received over either protocol.
+type collector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mux      sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	headers  []string
+	// failures is the number of requests to fail before accepting requests.
+	failures int
+}
+
+func (c *collector) receive(req *collogspb.ExportLogsServiceRequest, header string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		return false
+	}
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, header)
+	return true
+}
+
+func (c *collector) records() []*logspb.LogRecord {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var records []*logspb.LogRecord
+	for _, req := range c.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+func (c *collector) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		header = md.Get("authorization")[0]
+	}
+	c.receive(req, header)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !c.receive(&req, r.Header.Get("Authorization")) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// newTestLogger builds the sink and returns a zap.Logger that writes to it, with a
+// resource attached as it would be by log.Init.
+func newTestLogger(t *testing.T, opts Options) (*zap.Logger, *sink) {
+	t.Helper()
+	opts.setDefaults()
+	s := &sink{opts: opts}
+	c, err := s.Build()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	return zap.New(c).With(zap.Object(otelfields.ResourceFieldKey, &encoders.ResourceEncoder{
+		Resource: log.Resource{Name: "test-service", Version: "1.2.3"},
+	})), s
+}
+
+func TestSinkHTTP(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+
+	logger, _ := newTestLogger(t, Options{
+		Endpoint: srv.URL + "/v1/logs",
+		Protocol: ProtocolHTTPProtobuf,
+		Headers:  map[string]string{"Authorization": "token"},
+		Level:    log.LevelInfo,
+	})
+
+	logger.Named("foo").
+		With(zap.Inline(&encoders.TraceContextEncoder{TraceContext: log.TraceContext{
+			TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:     "00f067aa0ba902b7",
+			TraceFlags: log.TraceFlagsSampled,
+		}})).
+		Warn("hello", zap.String("some", "field"), zap.Int("n", 1))
+	logger.Debug("dropped")
+	require.NoError(t, logger.Sync())
+
+	require.Len(t, c.requests, 1)
+	assert.Equal(t, "token", c.headers[0])
+
+	rl := c.requests[0].ResourceLogs
+	require.Len(t, rl, 1)
+	resource := map[string]string{}
+	for _, kv := range rl[0].Resource.Attributes {
+		resource[kv.Key] = kv.Value.GetStringValue()
+	}
+	assert.Equal(t, "test-service", resource["service.name"])
+	assert.Equal(t, "1.2.3", resource["service.version"])
+
+	require.Len(t, rl[0].ScopeLogs, 1)
+	assert.Equal(t, "foo", rl[0].ScopeLogs[0].Scope.Name)
+
+	records := c.records()
+	require.Len(t, records, 1)
+	r := records[0]
+	assert.Equal(t, "hello", r.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, r.SeverityNumber)
+	assert.Equal(t, "WARN", r.SeverityText)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(r.TraceId))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(r.SpanId))
+	assert.Equal(t, uint32(1), r.Flags)
+
+	require.Len(t, r.Attributes, 2)
+	assert.Equal(t, "n", r.Attributes[0].Key)
+	assert.Equal(t, int64(1), r.Attributes[0].Value.GetIntValue())
+	assert.Equal(t, "some", r.Attributes[1].Key)
+	assert.Equal(t, "field", r.Attributes[1].Value.GetStringValue())
+}
+
+func TestSinkHTTPRetry(t *testing.T) {
+	c := &collector{failures: 2}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+
+	logger, _ := newTestLogger(t, Options{
+		Endpoint: srv.URL,
+		Protocol: ProtocolHTTPProtobuf,
+		Level:    log.LevelInfo,
+	})
+	logger.Info("hello")
+	require.NoError(t, logger.Sync())
+
+	assert.Len(t, c.records(), 1)
+}
+
+func TestSinkGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	c := &collector{}
+	srv := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(srv, c)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	logger, s := newTestLogger(t, Options{
+		Endpoint:     lis.Addr().String(),
+		Insecure:     true,
+		Headers:      map[string]string{"authorization": "token"},
+		Level:        log.LevelInfo,
+		BatchSize:    2,
+		BatchTimeout: time.Hour,
+	})
+
+	for i := 0; i < 5; i++ {
+		logger.Info("hello", zap.Int("i", i))
+	}
+	// Closing the sink flushes the remaining entries.
+	require.NoError(t, s.Close())
+
+	records := c.records()
+	require.Len(t, records, 5)
+	for i, r := range records {
+		assert.Equal(t, "hello", r.Body.GetStringValue())
+		assert.Equal(t, int64(i), r.Attributes[0].Value.GetIntValue())
+	}
+	// Batches of 2, 2, and 1
+	assert.Len(t, c.requests, 3)
+	assert.Equal(t, "token", c.headers[0])
+
+	// Logging after close is dropped without blocking
+	logger.Info("closed")
+	require.NoError(t, logger.Sync())
+	assert.Len(t, c.records(), 5)
+}
+
+func TestSinkRequiresEndpoint(t *testing.T) {
+	s := NewSink(Options{})
+	assert.Equal(t, "OTLPSink", s.Name())
+
+	_, err := (&sink{}).Build()
+	assert.Error(t, err)
+}
+
+func TestToSeverityNumber(t *testing.T) {
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, toSeverityNumber(zapcore.DebugLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, toSeverityNumber(zapcore.ErrorLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, toSeverityNumber(zapcore.FatalLevel))
+}
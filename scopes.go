@@ -0,0 +1,47 @@
+package log
+
+import (
+	"sort"
+	"sync"
+)
+
+// maxKnownScopes bounds the number of scopes recorded by KnownScopes, in case scopes
+// are created dynamically.
+const maxKnownScopes = 10000
+
+var knownScopes = struct {
+	sync.RWMutex
+	scopes map[string]struct{}
+}{scopes: make(map[string]struct{})}
+
+// recordScope records the full name of a scope that a logger was created with.
+func recordScope(scope string) {
+	knownScopes.RLock()
+	_, ok := knownScopes.scopes[scope]
+	full := len(knownScopes.scopes) >= maxKnownScopes
+	knownScopes.RUnlock()
+	if ok || full {
+		return
+	}
+
+	knownScopes.Lock()
+	if len(knownScopes.scopes) < maxKnownScopes {
+		knownScopes.scopes[scope] = struct{}{}
+	}
+	knownScopes.Unlock()
+}
+
+// KnownScopes returns the full names of all scopes that loggers have been created with
+// in this process, sorted by name. This is useful for discovering scopes to adjust the
+// levels of, see OutputSink.
+func KnownScopes() []string {
+	knownScopes.RLock()
+	defer knownScopes.RUnlock()
+
+	scopes := make([]string, 0, len(knownScopes.scopes))
+	for s := range knownScopes.scopes {
+		scopes = append(scopes, s)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
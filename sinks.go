@@ -1,11 +1,14 @@
 package log
 
 import (
+	"io"
+
 	"go.uber.org/zap/zapcore"
 )
 
 // Sink describes additional destinations that github.com/sourcegraph/log can send log
-// entries to. It can only be implemented directly within the package.
+// entries to. It can only be implemented directly within the package - to implement a
+// Sink elsewhere, implement CustomSink and provide it to Init with NewCustomSink.
 type Sink interface {
 	Name() string
 
@@ -20,6 +23,14 @@ type Sink interface {
 // SinksConfig describes unified configuration for all sinks.
 type SinksConfig struct {
 	Sentry *SentrySink
+	// Output configures the levels of the output sink. If nil, the current configuration
+	// of the output sink is left unchanged. Unset values within Output are restored from
+	// the environment.
+	Output *OutputSink
+
+	// Custom holds configuration for sinks created with NewCustomSink, keyed by the
+	// name of each sink. The value for each sink is only interpreted by the sink itself.
+	Custom map[string]interface{}
 }
 
 type sinks []Sink
@@ -39,6 +50,18 @@ func (s sinks) update(get SinksConfigGetter) func() {
 	}
 }
 
+// close closes all sinks that implement io.Closer.
+func (s sinks) close() {
+	for _, sink := range s {
+		if c, ok := sink.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				Scoped("log.sinks.close").
+					Error("failed to close", String("sink", sink.Name()), Error(err))
+			}
+		}
+	}
+}
+
 func (s sinks) build() ([]zapcore.Core, error) {
 	var cores []zapcore.Core
 
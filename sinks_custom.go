@@ -0,0 +1,111 @@
+package log
+
+import (
+	"io"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+// CustomSink describes a destination for log entries that is implemented outside of this
+// package. Use NewCustomSink to provide a CustomSink to Init.
+//
+// If a CustomSink implements io.Closer, Close is called when the Close callback returned
+// by Init is called, after all sinks have been synced.
+type CustomSink interface {
+	// Name identifies the sink. It is used to look up configuration for the sink in
+	// SinksConfig.Custom, and should be unique amongst all sinks provided to Init.
+	Name() string
+
+	// Build creates the core to attach to the root logger. The implementation should
+	// maintain a reference to anything needed to update this core, as a sink will only
+	// ever be built once.
+	//
+	// The core receives entries from all loggers, including a field holding the Resource
+	// the logger was initialized with (see ResourceFromField) and any trace context (see
+	// TraceContextFromField), and is responsible for its own level filtering. Sync on the
+	// core is called when the Sync callback returned by Init is called.
+	Build() (zapcore.Core, error)
+
+	// Update is called on the Update callback returned by Init with new configuration.
+	// Configuration specific to this sink is provided in SinksConfig.Custom under the
+	// name of this sink.
+	Update(SinksConfig) error
+}
+
+// ResourceFromField returns the Resource held by the given field, if the field is the
+// field that the root logger annotates all entries with. This is useful for retrieving
+// the Resource in cores built by a CustomSink.
+func ResourceFromField(f Field) (Resource, bool) {
+	if f.Key != otelfields.ResourceFieldKey {
+		return Resource{}, false
+	}
+	r, ok := f.Interface.(*encoders.ResourceEncoder)
+	if !ok {
+		return Resource{}, false
+	}
+	return r.Resource, true
+}
+
+// TraceContextFromField returns the TraceContext held by the given field, if the field
+// is the field that Logger.WithTrace annotates entries with. This is useful for
+// retrieving the trace context in cores built by a CustomSink.
+func TraceContextFromField(f Field) (TraceContext, bool) {
+	t, ok := f.Interface.(*encoders.TraceContextEncoder)
+	if !ok {
+		return TraceContext{}, false
+	}
+	return t.TraceContext, true
+}
+
+// ErrorFromField returns the original error held by the given field, if the field was
+// created with Error or NamedError. This is useful for reporting errors as such in cores
+// built by a CustomSink, or when passing fields on to another logging library.
+func ErrorFromField(f Field) (error, bool) {
+	if f.Type != zapcore.ErrorType {
+		return nil, false
+	}
+	if enc, ok := f.Interface.(*encoders.ErrorEncoder); ok {
+		return enc.Source, true
+	}
+	err, ok := f.Interface.(error)
+	return err, ok
+}
+
+// IsAttributesNamespace reports whether the given field is the namespace that the root
+// logger nests all attributes under in output. Cores built by a CustomSink that keep
+// attributes separately from the Resource and trace context can skip it.
+func IsAttributesNamespace(f Field) bool {
+	return f.Equals(otelfields.AttributesNamespace)
+}
+
+// MarshalResource encodes the given Resource with the same keys as it is output with,
+// e.g. "service.name". This is useful for cores built by a CustomSink that export the
+// Resource separately from entries.
+func MarshalResource(r Resource, enc zapcore.ObjectEncoder) error {
+	return (&encoders.ResourceEncoder{Resource: r}).MarshalLogObject(enc)
+}
+
+// NewCustomSink adapts a CustomSink into a Sink to provide to Init.
+func NewCustomSink(s CustomSink) Sink {
+	return &customSink{CustomSink: s}
+}
+
+type customSink struct {
+	CustomSink
+}
+
+var _ io.Closer = &customSink{}
+
+func (s *customSink) build() (zapcore.Core, error) { return s.Build() }
+
+func (s *customSink) update(updated SinksConfig) error { return s.Update(updated) }
+
+func (s *customSink) Close() error {
+	if c, ok := s.CustomSink.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
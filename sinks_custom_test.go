@@ -0,0 +1,104 @@
+package log
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+type testCustomSink struct {
+	core    zapcore.Core
+	updates []interface{}
+	closed  bool
+}
+
+func (s *testCustomSink) Name() string { return "TestSink" }
+
+func (s *testCustomSink) Build() (zapcore.Core, error) { return s.core, nil }
+
+func (s *testCustomSink) Update(c SinksConfig) error {
+	s.updates = append(s.updates, c.Custom[s.Name()])
+	return nil
+}
+
+func (s *testCustomSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestCustomSink(t *testing.T) {
+	core, entries := observer.New(zapcore.DebugLevel)
+	custom := &testCustomSink{core: core}
+	ss := sinks{NewCustomSink(custom)}
+
+	cores, err := ss.build()
+	require.NoError(t, err)
+	require.Len(t, cores, 1)
+
+	_ = cores[0].Write(zapcore.Entry{Message: "hello"}, nil)
+	assert.Equal(t, 1, entries.Len())
+
+	ss.update(func() SinksConfig {
+		return SinksConfig{Custom: map[string]interface{}{"TestSink": "config"}}
+	})()
+	assert.Equal(t, []interface{}{"config"}, custom.updates)
+
+	ss.close()
+	assert.True(t, custom.closed)
+}
+
+func TestResourceFromField(t *testing.T) {
+	r, ok := ResourceFromField(String("foo", "bar"))
+	assert.False(t, ok)
+	assert.Equal(t, Resource{}, r)
+
+	r, ok = ResourceFromField(Field{
+		Key:       otelfields.ResourceFieldKey,
+		Type:      zapcore.ObjectMarshalerType,
+		Interface: &encoders.ResourceEncoder{Resource: Resource{Name: "foo"}},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "foo", r.Name)
+}
+
+func TestTraceContextFromField(t *testing.T) {
+	_, ok := TraceContextFromField(String("foo", "bar"))
+	assert.False(t, ok)
+
+	tc, ok := TraceContextFromField(zap.Inline(&encoders.TraceContextEncoder{
+		TraceContext: TraceContext{TraceID: "123"},
+	}))
+	assert.True(t, ok)
+	assert.Equal(t, "123", tc.TraceID)
+}
+
+func TestErrorFromField(t *testing.T) {
+	_, ok := ErrorFromField(String("foo", "bar"))
+	assert.False(t, ok)
+
+	want := errors.New("oops")
+	err, ok := ErrorFromField(Error(want))
+	assert.True(t, ok)
+	assert.Equal(t, want, err)
+
+	err, ok = ErrorFromField(zap.NamedError("err", want))
+	assert.True(t, ok)
+	assert.Equal(t, want, err)
+}
+
+func TestMarshalResource(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	require.NoError(t, MarshalResource(Resource{Name: "foo", Version: "1.0"}, enc))
+	assert.Equal(t, map[string]interface{}{
+		"service.name":    "foo",
+		"service.version": "1.0",
+	}, enc.Fields)
+}
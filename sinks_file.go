@@ -0,0 +1,95 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/rotate"
+	"github.com/sourcegraph/log/internal/sinkcores/outputcore"
+	"github.com/sourcegraph/log/output"
+)
+
+// FileSink writes log entries to a file, optionally rotating the file based on its size
+// and age. This is useful for deployments without a log collector that can consume
+// output written to stderr.
+type FileSink struct {
+	// Path is the path of the file to write to. Parent directories are created if they
+	// do not exist. Required.
+	Path string
+	// Format is the format to write entries in. Defaults to output.FormatJSON.
+	Format output.Format
+	// Level is the minimum level of entries to write. Defaults to the level configured
+	// with EnvLogLevel.
+	Level Level
+
+	// MaxSize is the size in bytes after which the file is rotated. If zero, the file is
+	// not rotated based on size.
+	MaxSize int64
+	// MaxAge is the duration after which the file is rotated, measured from when the
+	// file was opened. If zero, the file is not rotated based on age.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to retain. If zero, all rotated files
+	// are retained.
+	MaxBackups int
+	// Compress indicates if rotated files should be compressed with gzip.
+	Compress bool
+}
+
+type fileSink struct {
+	FileSink
+
+	writer *rotate.Writer
+}
+
+var _ io.Closer = &fileSink{}
+
+// NewFileSink instantiates a file sink to provide to `log.Init` with the values provided
+// in FileSink.
+func NewFileSink(s FileSink) Sink {
+	return &fileSink{FileSink: s}
+}
+
+func (s *fileSink) Name() string { return "FileSink" }
+
+func (s *fileSink) build() (zapcore.Core, error) {
+	if s.Path == "" {
+		return nil, errors.New("FileSink: Path is required")
+	}
+
+	w, err := rotate.Open(s.Path, rotate.Options{
+		MaxSize:    s.MaxSize,
+		MaxAge:     s.MaxAge,
+		MaxBackups: s.MaxBackups,
+		Compress:   s.Compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.writer = w
+
+	level := s.Level
+	if level == "" {
+		level = Level(os.Getenv(EnvLogLevel))
+	}
+	format := s.Format
+	if format == "" {
+		format = output.FormatJSON
+	}
+
+	return outputcore.NewCore(w, level.Parse(), format, zap.SamplingConfig{}, nil, false), nil
+}
+
+// update is a no-op because fileSink cannot be changed live.
+func (s *fileSink) update(SinksConfig) error { return nil }
+
+func (s *fileSink) Close() error {
+	if s.writer == nil {
+		return nil
+	}
+	return s.writer.Close()
+}
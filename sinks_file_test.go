@@ -0,0 +1,79 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readFileEntries returns the messages of the JSON entries in the file at path.
+func readFileEntries(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		messages = append(messages, entry["Body"].(string))
+	}
+	return messages
+}
+
+func TestFileSink(t *testing.T) {
+	t.Run("path is required", func(t *testing.T) {
+		_, err := sinks{NewFileSink(FileSink{})}.build()
+		assert.ErrorContains(t, err, "Path is required")
+	})
+
+	t.Run("rotates by size and retains backups", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		logger, callbacks := initSinks(t, "foo", NewFileSink(FileSink{
+			Path:       path,
+			Level:      LevelInfo,
+			MaxSize:    1024,
+			MaxBackups: 2,
+		}))
+
+		message := strings.Repeat("a", 400)
+		for i := 0; i < 10; i++ {
+			logger.Info(message, Int("i", i))
+		}
+		logger.Debug("dropped")
+		callbacks.Close()
+
+		names, err := filepath.Glob(filepath.Join(dir, "app-*.log"))
+		require.NoError(t, err)
+		require.Len(t, names, 2)
+		sort.Strings(names)
+
+		// Entries are never split across files, and the oldest backups are removed
+		var messages []string
+		for _, name := range append(names, path) {
+			entries := readFileEntries(t, name)
+			assert.NotEmpty(t, entries)
+			messages = append(messages, entries...)
+		}
+		assert.Less(t, len(messages), 10)
+		for _, m := range messages {
+			assert.Equal(t, message, m)
+		}
+	})
+
+	t.Run("appends when reopened", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "logs", "app.log")
+		for _, message := range []string{"first", "second"} {
+			logger, callbacks := initSinks(t, "foo", NewFileSink(FileSink{Path: path}))
+			logger.Warn(message)
+			callbacks.Close()
+		}
+		assert.Equal(t, []string{"first", "second"}, readFileEntries(t, path))
+	})
+}
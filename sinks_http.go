@@ -0,0 +1,158 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/httpbatch"
+	"github.com/sourcegraph/log/internal/sinkcores/outputcore"
+	"github.com/sourcegraph/log/output"
+)
+
+const (
+	defaultHTTPBatchEntries  = 1000
+	defaultHTTPBatchBytes    = 1 << 20 // 1 MiB
+	defaultHTTPBatchTimeout  = time.Second
+	defaultHTTPBufferBytes   = 16 << 20 // 16 MiB
+	defaultHTTPTimeout       = 10 * time.Second
+	defaultHTTPMaxRetryTime  = time.Minute
+	defaultHTTPMaxSpoolBytes = 256 << 20 // 256 MiB
+)
+
+// HTTPSink sends log entries in batches to an HTTP endpoint, with each batch POSTed as
+// newline-delimited JSON. This can be used to send entries to any collector that
+// accepts JSON lines, such as Vector or Elasticsearch.
+//
+// Batches are sent when they reach BatchEntries or BatchBytes, or BatchTimeout after
+// the first entry in the batch was logged. Failed requests are retried with exponential
+// backoff if the failure is transient, i.e. network errors, 429 and 5xx responses.
+type HTTPSink struct {
+	// URL is the endpoint to POST batches to. Required.
+	URL string
+	// Headers are sent with each request, e.g. for authentication.
+	Headers map[string]string
+	// Format is the format to encode entries in, either output.FormatJSON or
+	// output.FormatJSONGCP. Defaults to output.FormatJSON.
+	Format output.Format
+	// Level is the minimum level of entries to send. Defaults to the level configured
+	// with EnvLogLevel.
+	Level Level
+
+	// BatchEntries is the maximum number of entries in a batch. Defaults to 1000.
+	BatchEntries int
+	// BatchBytes is the maximum size of a batch in bytes. Defaults to 1 MiB.
+	BatchBytes int
+	// BatchTimeout is the maximum duration to wait before sending a batch that is not
+	// full. Defaults to 1 second.
+	BatchTimeout time.Duration
+	// MaxBufferBytes is the maximum size of entries held in memory waiting to be sent.
+	// Entries logged while the buffer is full are dropped, and counted in HTTPStats.
+	// Defaults to 16 MiB.
+	MaxBufferBytes int
+
+	// Timeout is the timeout of each request. Defaults to 10 seconds.
+	Timeout time.Duration
+	// MaxRetryTime is the maximum duration to spend retrying a batch before dropping
+	// it, if SpoolDir is not set. Defaults to 1 minute.
+	MaxRetryTime time.Duration
+
+	// SpoolDir, if set, is a directory that batches which could not be sent are written
+	// to. Spooled batches are retried, in order, until they are sent, including by
+	// later processes using the same SpoolDir - this allows entries to survive
+	// collector outages and restarts. SpoolDir must not be shared by multiple sinks or
+	// processes at the same time.
+	SpoolDir string
+	// MaxSpoolBytes is the maximum size of SpoolDir. If the spool would exceed this,
+	// the oldest batches are dropped. Defaults to 256 MiB.
+	MaxSpoolBytes int64
+}
+
+// HTTPStats holds cumulative counts of entries that the HTTP sink did not send.
+type HTTPStats struct {
+	// Dropped is the number of entries dropped because the buffer was full, see
+	// HTTPSink.MaxBufferBytes.
+	Dropped uint64
+}
+
+type httpSink struct {
+	HTTPSink
+
+	writer *httpbatch.Writer
+}
+
+var _ io.Closer = &httpSink{}
+
+// NewHTTPSink instantiates an HTTP sink to provide to `log.Init` with the values
+// provided in HTTPSink.
+func NewHTTPSink(s HTTPSink) Sink {
+	return &httpSink{HTTPSink: s}
+}
+
+func (s *httpSink) Name() string { return "HTTPSink" }
+
+func (s *httpSink) build() (zapcore.Core, error) {
+	if s.URL == "" {
+		return nil, errors.New("HTTPSink: URL is required")
+	}
+	format := s.Format
+	switch format {
+	case "":
+		format = output.FormatJSON
+	case output.FormatJSON, output.FormatJSONGCP:
+	default:
+		return nil, errors.New("HTTPSink: Format must be a JSON format")
+	}
+
+	w, err := httpbatch.Open(httpbatch.Options{
+		URL:             s.URL,
+		ContentType:     "application/x-ndjson",
+		Headers:         s.Headers,
+		MaxBatchEntries: withDefault(s.BatchEntries, defaultHTTPBatchEntries),
+		MaxBatchBytes:   withDefault(s.BatchBytes, defaultHTTPBatchBytes),
+		BatchTimeout:    withDefault(s.BatchTimeout, defaultHTTPBatchTimeout),
+		MaxBufferBytes:  withDefault(s.MaxBufferBytes, defaultHTTPBufferBytes),
+		RequestTimeout:  withDefault(s.Timeout, defaultHTTPTimeout),
+		MaxElapsedTime:  withDefault(s.MaxRetryTime, defaultHTTPMaxRetryTime),
+		SpoolDir:        s.SpoolDir,
+		MaxSpoolBytes:   withDefault(s.MaxSpoolBytes, defaultHTTPMaxSpoolBytes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.writer = w
+
+	level := s.Level
+	if level == "" {
+		level = Level(os.Getenv(EnvLogLevel))
+	}
+
+	return outputcore.NewCore(w, level.Parse(), format, zap.SamplingConfig{}, nil, false), nil
+}
+
+// stats returns the cumulative counts of entries that were not sent.
+func (s *httpSink) stats() HTTPStats {
+	return HTTPStats{Dropped: s.writer.Dropped()}
+}
+
+// update is a no-op because httpSink cannot be changed live.
+func (s *httpSink) update(SinksConfig) error { return nil }
+
+func (s *httpSink) Close() error {
+	if s.writer == nil {
+		return nil
+	}
+	return s.writer.Close()
+}
+
+// withDefault returns v, or def if v is not positive.
+func withDefault[T int | int64 | time.Duration](v, def T) T {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,151 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sourcegraph/log/output"
+)
+
+// httpCollector is a test server that records the messages of each batch it accepts.
+type httpCollector struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	status  int
+	batches [][]string
+}
+
+func newHTTPCollector(t *testing.T) *httpCollector {
+	c := &httpCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "token", r.Header.Get("Authorization"))
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.status != 0 {
+			w.WriteHeader(c.status)
+			return
+		}
+		var batch []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			batch = append(batch, entry["Body"].(string))
+		}
+		c.batches = append(c.batches, batch)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// setStatus makes the collector reject requests with status, or accept them if status
+// is 0.
+func (c *httpCollector) setStatus(status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+}
+
+func (c *httpCollector) received() [][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]string(nil), c.batches...)
+}
+
+func TestHTTPSink(t *testing.T) {
+	t.Run("format must be JSON", func(t *testing.T) {
+		_, err := sinks{NewHTTPSink(HTTPSink{URL: "http://localhost", Format: output.FormatConsole})}.build()
+		assert.ErrorContains(t, err, "Format must be a JSON format")
+	})
+
+	t.Run("batches entries", func(t *testing.T) {
+		collector := newHTTPCollector(t)
+		logger, callbacks := initSinks(t, "foo", NewHTTPSink(HTTPSink{
+			URL:          collector.URL,
+			Headers:      map[string]string{"Authorization": "token"},
+			Level:        LevelInfo,
+			BatchEntries: 2,
+		}))
+
+		logger.Debug("dropped")
+		for _, message := range []string{"a", "b", "c"} {
+			logger.Info(message)
+		}
+		callbacks.Sync()
+		assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, collector.received())
+	})
+
+	t.Run("counts entries dropped when the buffer is full", func(t *testing.T) {
+		collector := newHTTPCollector(t)
+		logger, callbacks := initSinks(t, "foo", NewHTTPSink(HTTPSink{
+			URL:            collector.URL,
+			Headers:        map[string]string{"Authorization": "token"},
+			MaxBufferBytes: 1024,
+		}))
+
+		logger.Warn(strings.Repeat("a", 600))
+		logger.Warn(strings.Repeat("b", 600))
+		callbacks.Sync()
+		assert.Len(t, collector.received(), 1)
+		stats, ok := callbacks.HTTPStats()
+		assert.True(t, ok)
+		assert.Equal(t, HTTPStats{Dropped: 1}, stats)
+	})
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		collector := newHTTPCollector(t)
+		collector.setStatus(http.StatusServiceUnavailable)
+		logger, callbacks := initSinks(t, "foo", NewHTTPSink(HTTPSink{
+			URL:          collector.URL,
+			Headers:      map[string]string{"Authorization": "token"},
+			BatchTimeout: 10 * time.Millisecond,
+		}))
+
+		logger.Warn("retried")
+		time.AfterFunc(100*time.Millisecond, func() { collector.setStatus(0) })
+		assert.Eventually(t, func() bool { return len(collector.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+		callbacks.Close()
+		assert.Equal(t, [][]string{{"retried"}}, collector.received())
+	})
+
+	t.Run("spools entries across restarts", func(t *testing.T) {
+		spool := t.TempDir()
+		collector := newHTTPCollector(t)
+		collector.setStatus(http.StatusServiceUnavailable)
+		sink := HTTPSink{
+			URL:      collector.URL,
+			Headers:  map[string]string{"Authorization": "token"},
+			SpoolDir: spool,
+		}
+
+		logger, callbacks := initSinks(t, "foo", NewHTTPSink(sink))
+		logger.Warn("spooled")
+		callbacks.Close()
+		spooled, err := os.ReadDir(spool)
+		require.NoError(t, err)
+		assert.Len(t, spooled, 1)
+
+		// A later process using the same spool sends the spooled entries
+		collector.setStatus(0)
+		logger, callbacks = initSinks(t, "foo", NewHTTPSink(sink))
+		logger.Warn("sent")
+		callbacks.Sync()
+		assert.Equal(t, [][]string{{"spooled"}, {"sent"}}, collector.received())
+		spooled, err = os.ReadDir(spool)
+		require.NoError(t, err)
+		assert.Empty(t, spooled)
+	})
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readJournalFields reads a single entry in the native journal protocol from server.
+func readJournalFields(t *testing.T, server *net.UnixConn) map[string]string {
+	t.Helper()
+	buf := make([]byte, 64<<10)
+	require.NoError(t, server.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := server.Read(buf)
+	require.NoError(t, err)
+
+	fields := make(map[string]string)
+	data := buf[:n]
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i]
+			data = data[i+1:]
+		} else {
+			data = nil
+		}
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			continue
+		}
+		// Binary-safe format: the name, then the little-endian length of the value
+		size := binary.LittleEndian.Uint64(data[:8])
+		fields[string(line)] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return fields
+}
+
+func TestJournaldSink(t *testing.T) {
+	t.Run("socket must exist", func(t *testing.T) {
+		_, err := sinks{NewJournaldSink(JournaldSink{
+			Socket: filepath.Join(t.TempDir(), "socket"),
+		})}.build()
+		assert.ErrorContains(t, err, "JournaldSink")
+	})
+
+	t.Run("maps entries to journal fields", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "socket")
+		server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = server.Close() })
+
+		logger, _ := initSinks(t, "foo", NewJournaldSink(JournaldSink{Socket: path, Level: LevelInfo}))
+		logger.Debug("dropped")
+		logger.Scoped("bar").Warn("multi\nline",
+			String("user", "alice"),
+			Object("req", String("id", "abc")),
+			String("message", "clashes"),
+		)
+
+		// The Resource and attributes are sent as uppercase fields, and attributes that
+		// clash with fields with special meaning are prefixed
+		assert.Equal(t, map[string]string{
+			"MESSAGE":             "multi\nline",
+			"PRIORITY":            "4",
+			"SYSLOG_IDENTIFIER":   "foo.bar",
+			"SERVICE_NAME":        "test-service",
+			"SERVICE_INSTANCE_ID": "test-instance",
+			"USER":                "alice",
+			"REQ_ID":              "abc",
+			"ATTR_MESSAGE":        "clashes",
+		}, readJournalFields(t, server))
+	})
+}
@@ -0,0 +1,203 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/scopematch"
+	"github.com/sourcegraph/log/internal/sinkcores/memorycore"
+)
+
+const (
+	// defaultMemoryMaxEntries is the default maximum number of entries kept by a
+	// memory sink.
+	defaultMemoryMaxEntries = 10000
+	// defaultMemoryMaxBytes is the default maximum approximate size of entries kept by
+	// a memory sink.
+	defaultMemoryMaxBytes = 16 << 20
+)
+
+// MemorySink keeps the most recent log entries in memory, so that they can be queried and
+// exported with MemoryLogs - for example to surface recent logs in an admin UI, or to
+// include them in support bundles.
+type MemorySink struct {
+	// MaxEntries is the maximum number of entries to keep. Defaults to 10000.
+	MaxEntries int
+	// MaxBytes is the maximum approximate size of the entries to keep, as encoded in
+	// JSON. Defaults to 16 MiB.
+	MaxBytes int
+	// Level is the minimum level of entries to keep. Defaults to the level configured
+	// with EnvLogLevel.
+	Level Level
+}
+
+// MemoryLogs is a sink created with NewMemorySink, which holds the most recent log
+// entries. It can be queried both before and after it is provided to Init, but it only
+// holds entries after Init.
+type MemoryLogs struct {
+	MemorySink
+
+	buffer *memorycore.Buffer
+}
+
+var _ Sink = &MemoryLogs{}
+
+// NewMemorySink instantiates a memory sink to provide to `log.Init` with the values
+// provided in MemorySink. The returned MemoryLogs is used to query recent entries.
+func NewMemorySink(s MemorySink) *MemoryLogs {
+	if s.MaxEntries <= 0 {
+		s.MaxEntries = defaultMemoryMaxEntries
+	}
+	if s.MaxBytes <= 0 {
+		s.MaxBytes = defaultMemoryMaxBytes
+	}
+	return &MemoryLogs{
+		MemorySink: s,
+		buffer:     memorycore.NewBuffer(s.MaxEntries, s.MaxBytes),
+	}
+}
+
+func (s *MemoryLogs) Name() string { return "MemorySink" }
+
+func (s *MemoryLogs) build() (zapcore.Core, error) {
+	level := s.Level
+	if level == "" {
+		level = Level(os.Getenv(EnvLogLevel))
+	}
+	return memorycore.NewCore(level.Parse(), s.buffer), nil
+}
+
+// update is a no-op because the memory sink cannot be changed live.
+func (s *MemoryLogs) update(SinksConfig) error { return nil }
+
+// MemoryQuery selects entries from MemoryLogs. The zero value selects all entries.
+type MemoryQuery struct {
+	// Level is the minimum level of entries to select.
+	Level Level
+	// Scope is a scope pattern as described in EnvLogScopeLevel, which selects entries
+	// from matching scopes and their children.
+	Scope string
+	// Since and Until select entries logged at or after Since, and before Until.
+	Since, Until time.Time
+	// Fields selects entries with the given field values, keyed by field name. Fields
+	// nested in namespaces or objects are named by joining their keys with '.', and
+	// values are compared in their string form, e.g. "42" or "true".
+	Fields map[string]string
+	// Limit selects at most the given number of the most recent matching entries.
+	Limit int
+}
+
+// MemoryEntry is a log entry kept by MemoryLogs.
+type MemoryEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Scope   string                 `json:"scope"`
+	Message string                 `json:"message"`
+	Caller  string                 `json:"caller,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Query returns the entries that match q, from oldest to newest.
+func (s *MemoryLogs) Query(q MemoryQuery) ([]MemoryEntry, error) {
+	match, err := q.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := s.buffer.Entries(match)
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+
+	results := make([]MemoryEntry, len(entries))
+	for i, e := range entries {
+		results[i] = MemoryEntry{
+			Time:    e.Time,
+			Level:   e.Level.String(),
+			Scope:   e.Scope,
+			Message: e.Message,
+			Caller:  e.Caller,
+			Fields:  e.Fields,
+		}
+	}
+	return results, nil
+}
+
+// WriteJSON writes the entries that match q to w as a JSON array, from oldest to newest.
+func (s *MemoryLogs) WriteJSON(w io.Writer, q MemoryQuery) error {
+	entries, err := s.Query(q)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// matcher returns a function that reports whether an entry matches q.
+func (q MemoryQuery) matcher() (func(*memorycore.Entry) bool, error) {
+	var matchers []func(*memorycore.Entry) bool
+
+	if q.Level != "" {
+		level := q.Level.Parse()
+		matchers = append(matchers, func(e *memorycore.Entry) bool { return e.Level >= level })
+	}
+	if q.Scope != "" {
+		pattern, err := scopematch.Parse(q.Scope)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, func(e *memorycore.Entry) bool {
+			_, ok := pattern.Match(e.Scope)
+			return ok
+		})
+	}
+	if !q.Since.IsZero() {
+		matchers = append(matchers, func(e *memorycore.Entry) bool { return !e.Time.Before(q.Since) })
+	}
+	if !q.Until.IsZero() {
+		matchers = append(matchers, func(e *memorycore.Entry) bool { return e.Time.Before(q.Until) })
+	}
+	for key, value := range q.Fields {
+		key, value := key, value
+		matchers = append(matchers, func(e *memorycore.Entry) bool {
+			v, ok := lookupField(e.Fields, key)
+			return ok && fmt.Sprint(v) == value
+		})
+	}
+
+	return func(e *memorycore.Entry) bool {
+		for _, match := range matchers {
+			if !match(e) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// lookupField returns the value of the field named key in fields, where fields nested in
+// maps are named by joining their keys with '.'.
+func lookupField(fields map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := fields[key]; ok {
+		return v, true
+	}
+	// Try each prefix of key that ends before a '.' as the key of a nested map
+	for i := strings.Index(key, "."); i >= 0; {
+		if nested, ok := fields[key[:i]].(map[string]interface{}); ok {
+			if v, ok := lookupField(nested, key[i+1:]); ok {
+				return v, true
+			}
+		}
+		j := strings.Index(key[i+1:], ".")
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return nil, false
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMemorySink(t *testing.T) {
+	s := NewMemorySink(MemorySink{Level: LevelDebug})
+	core, err := s.build()
+	require.NoError(t, err)
+
+	start := time.Now().Round(0) // strip the monotonic clock reading
+	write := func(offset time.Duration, scope string, level zapcore.Level, msg string, fields ...Field) {
+		ent := zapcore.Entry{Time: start.Add(offset), LoggerName: scope, Level: level, Message: msg}
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write(fields...)
+		}
+	}
+	write(0, "foo", zapcore.DebugLevel, "debug", Int("n", 1))
+	write(time.Second, "foo.bar", zapcore.InfoLevel, "info", String("user", "alice"))
+	write(2*time.Second, "baz", zapcore.ErrorLevel, "error", Object("req", String("id", "abc")))
+	write(3*time.Second, "foo", zapcore.WarnLevel, "warn", zap.Bool("retry", true))
+
+	messages := func(q MemoryQuery) []string {
+		t.Helper()
+		entries, err := s.Query(q)
+		require.NoError(t, err)
+		var msgs []string
+		for _, e := range entries {
+			msgs = append(msgs, e.Message)
+		}
+		return msgs
+	}
+
+	assert.Equal(t, []string{"debug", "info", "error", "warn"}, messages(MemoryQuery{}))
+	assert.Equal(t, []string{"error", "warn"}, messages(MemoryQuery{Level: LevelWarn}))
+	assert.Equal(t, []string{"debug", "info", "warn"}, messages(MemoryQuery{Scope: "foo"}))
+	assert.Equal(t, []string{"info"}, messages(MemoryQuery{Scope: "*.bar"}))
+	assert.Equal(t, []string{"info", "error"}, messages(MemoryQuery{
+		Since: start.Add(time.Second),
+		Until: start.Add(3 * time.Second),
+	}))
+	assert.Equal(t, []string{"info"}, messages(MemoryQuery{Fields: map[string]string{"user": "alice"}}))
+	assert.Equal(t, []string{"debug"}, messages(MemoryQuery{Fields: map[string]string{"n": "1"}}))
+	assert.Equal(t, []string{"error"}, messages(MemoryQuery{Fields: map[string]string{"req.id": "abc"}}))
+	assert.Equal(t, []string{"warn"}, messages(MemoryQuery{Fields: map[string]string{"retry": "true"}}))
+	assert.Empty(t, messages(MemoryQuery{Fields: map[string]string{"user": "bob"}}))
+	assert.Equal(t, []string{"error", "warn"}, messages(MemoryQuery{Limit: 2}))
+
+	_, err = s.Query(MemoryQuery{Scope: "foo..bar"})
+	assert.Error(t, err)
+
+	t.Run("WriteJSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, s.WriteJSON(&buf, MemoryQuery{Level: LevelError}))
+
+		var entries []map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entries))
+		require.Len(t, entries, 1)
+		assert.Equal(t, "error", entries[0]["level"])
+		assert.Equal(t, "baz", entries[0]["scope"])
+		assert.Equal(t, map[string]interface{}{"req": map[string]interface{}{"id": "abc"}}, entries[0]["fields"])
+	})
+}
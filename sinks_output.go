@@ -3,19 +3,91 @@ package log
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/sourcegraph/log/internal/scopematch"
 	"github.com/sourcegraph/log/internal/sinkcores/outputcore"
 	"github.com/sourcegraph/log/internal/stderr"
 	"github.com/sourcegraph/log/output"
 )
 
+// OutputSink configures the output sink, which writes all log entries to stderr and is
+// always enabled. Its configuration can be changed at runtime with the Update callback
+// returned by Init, for example to enable debug logging for a specific scope in a live
+// service.
+type OutputSink struct {
+	// Level is the level to log at. If empty, the level configured with EnvLogLevel is
+	// used.
+	Level Level
+	// ScopeLevels overrides Level for specific scopes and their children, keyed by
+	// scope patterns as described in EnvLogScopeLevel. If a scope matches multiple
+	// entries, the most specific one is used. If nil, the overrides configured with
+	// EnvLogScopeLevel are used.
+	ScopeLevels map[string]Level
+	// Sampling configures sampling of entries with identical messages. If nil, the
+	// sampling configured with EnvLogSamplingInitial and EnvLogSamplingThereafter is
+	// used.
+	Sampling *OutputSampling
+	// SamplingRules overrides Sampling for entries from specific scopes and levels, for
+	// example to never sample errors, or to heavily sample a chatty scope. If nil, the
+	// rules configured with EnvLogSamplingRules are used.
+	SamplingRules []OutputSamplingRule
+}
+
+// OutputSampling configures sampling of entries with identical messages in the output
+// sink.
+//
+// For each scope, level and message with dropped entries, the output sink periodically
+// logs a summary of how many entries were dropped. Cumulative counts are available from
+// PostInitCallbacks.SamplingDrops.
+type OutputSampling struct {
+	// Initial is the number of entries with identical messages to always output per
+	// second. Set to 0 to disable sampling.
+	Initial int
+	// Thereafter is the number of entries with identical messages to discard before
+	// emitting another one per second, after Initial.
+	Thereafter int
+}
+
+// OutputSamplingRule overrides the sampling of entries from specific scopes and levels in
+// the output sink.
+type OutputSamplingRule struct {
+	// Scope is a scope pattern as described in EnvLogScopeLevel. If multiple rules
+	// match an entry, the rule with the most specific scope is used, preferring rules
+	// with a Level.
+	Scope string
+	// Level restricts the rule to entries at this level. If empty, the rule applies to
+	// entries at all levels.
+	Level Level
+	// Initial and Thereafter are as described in OutputSampling.
+	Initial    int
+	Thereafter int
+}
+
+// SamplingDrop is the number of entries from a scope with a level and message that were
+// dropped by sampling in the output sink. Once many distinct entries have been dropped,
+// further drops are counted with an empty Scope and Message.
+type SamplingDrop struct {
+	Scope   string
+	Level   Level
+	Message string
+	Dropped uint64
+}
+
 type outputSink struct {
 	development bool
+
+	// levels and sampling are set on build, and hold the current configuration of the
+	// core.
+	levels   *scopematch.Levels
+	sampling *outputcore.Sampling
+	drops    *outputcore.Drops
 }
 
 func (s *outputSink) Name() string { return "OutputSink" }
@@ -26,28 +98,163 @@ func (s *outputSink) build() (zapcore.Core, error) {
 		return nil, err
 	}
 
-	level := zap.NewAtomicLevelAt(Level(os.Getenv(EnvLogLevel)).Parse())
 	format := output.ParseFormat(os.Getenv(EnvLogFormat))
 
 	if s.development {
 		format = output.FormatConsole
 	}
 
-	sampling, err := parseSamplingConfig()
+	sampling, rules, err := outputSampling(nil)
 	if err != nil {
 		return nil, err
 	}
+	s.sampling = outputcore.NewSampling(sampling, rules)
+	s.drops = outputcore.NewDrops(outputcore.DefaultDropReportInterval)
 
-	overrides, err := parseOverrides()
+	dedup, err := parseDedupOptions()
 	if err != nil {
 		return nil, err
 	}
 
-	return outputcore.NewCore(w, level, format, sampling, overrides, s.development), nil
+	level, overrides, err := outputLevels(nil)
+	if err != nil {
+		return nil, err
+	}
+	s.levels = scopematch.NewLevels(level, overrides)
+
+	recorder, enabled, err := parseFlightRecorderOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	core := outputcore.NewDynamicCore(w, s.levels, s.sampling, s.drops, dedup, format, s.development)
+	if enabled {
+		core = outputcore.NewFlightRecorderCore(core, s.levels, recorder)
+	}
+	return core, nil
+}
+
+// update changes the configuration of the output sink, restoring unset values from the
+// environment. If updated.Output is nil, the current configuration is left unchanged.
+func (s *outputSink) update(updated SinksConfig) error {
+	if s == nil || s.levels == nil {
+		return nil // not built
+	}
+	if updated.Output == nil {
+		// Leave the current configuration, which may have been changed with
+		// UpdateOutput, as is
+		return nil
+	}
+
+	sampling, rules, err := outputSampling(updated.Output)
+	if err != nil {
+		return err
+	}
+	level, overrides, err := outputLevels(updated.Output)
+	if err != nil {
+		return err
+	}
+	s.sampling.Set(sampling, rules)
+	s.levels.Set(level, overrides)
+	return nil
 }
 
-// update is a no-op because outputSink cannot be changed live.
-func (s *outputSink) update(updated SinksConfig) error { return nil }
+// config returns the current configuration of the output sink.
+func (s *outputSink) config() OutputSink {
+	if s == nil || s.levels == nil {
+		return OutputSink{} // not built
+	}
+
+	level, overrides := s.levels.Get()
+	sampling, rules := s.sampling.Get()
+	return OutputSink{
+		Level:       levelFromZap(level),
+		ScopeLevels: fromOverrides(overrides),
+		Sampling: &OutputSampling{
+			Initial:    sampling.Initial,
+			Thereafter: sampling.Thereafter,
+		},
+		SamplingRules: fromSamplingRules(rules),
+	}
+}
+
+// samplingDrops returns the cumulative number of entries dropped by sampling, most
+// dropped first.
+func (s *outputSink) samplingDrops() []SamplingDrop {
+	if s == nil || s.drops == nil {
+		return nil // not built
+	}
+
+	counts := s.drops.Counts()
+	drops := make([]SamplingDrop, 0, len(counts))
+	for k, n := range counts {
+		drops = append(drops, SamplingDrop{
+			Scope:   k.Scope,
+			Level:   levelFromZap(k.Level),
+			Message: k.Message,
+			Dropped: n,
+		})
+	}
+	sort.Slice(drops, func(i, j int) bool {
+		if drops[i].Dropped != drops[j].Dropped {
+			return drops[i].Dropped > drops[j].Dropped
+		}
+		if drops[i].Scope != drops[j].Scope {
+			return drops[i].Scope < drops[j].Scope
+		}
+		return drops[i].Message < drops[j].Message
+	})
+	return drops
+}
+
+// outputSampling returns the sampling configuration and rules to use for the given
+// configuration, falling back to the environment for any unset configuration.
+func outputSampling(config *OutputSink) (zap.SamplingConfig, []outputcore.SamplingRule, error) {
+	if config == nil {
+		config = &OutputSink{}
+	}
+
+	var sampling zap.SamplingConfig
+	if config.Sampling == nil {
+		var err error
+		sampling, err = parseSamplingConfig()
+		if err != nil {
+			return sampling, nil, err
+		}
+	} else {
+		sampling = zap.SamplingConfig{
+			Initial:    config.Sampling.Initial,
+			Thereafter: config.Sampling.Thereafter,
+		}
+	}
+
+	if config.SamplingRules == nil {
+		rules, err := parseSamplingRules()
+		return sampling, rules, err
+	}
+	rules, err := toSamplingRules(config.SamplingRules)
+	return sampling, rules, err
+}
+
+// outputLevels returns the root level and scope overrides to use for the given
+// configuration, falling back to the environment for any unset configuration.
+func outputLevels(config *OutputSink) (zapcore.Level, []scopematch.Override, error) {
+	if config == nil {
+		config = &OutputSink{}
+	}
+
+	level := config.Level
+	if level == "" {
+		level = Level(os.Getenv(EnvLogLevel))
+	}
+
+	if config.ScopeLevels == nil {
+		overrides, err := parseOverrides(EnvLogScopeLevel)
+		return level.Parse(), overrides, err
+	}
+	overrides, err := toOverrides(config.ScopeLevels)
+	return level.Parse(), overrides, err
+}
 
 func parseSamplingConfig() (config zap.SamplingConfig, err error) {
 	if val, set := os.LookupEnv(EnvLogSamplingInitial); set {
@@ -60,7 +267,7 @@ func parseSamplingConfig() (config zap.SamplingConfig, err error) {
 		config.Initial = 100
 	}
 
-	if val, set := os.LookupEnv(EnvLogSamplingInitial); set {
+	if val, set := os.LookupEnv(EnvLogSamplingThereafter); set {
 		config.Thereafter, err = strconv.Atoi(val)
 		if err != nil {
 			err = fmt.Errorf("SRC_LOG_SAMPLING_THEREAFTER is invalid: %w", err)
@@ -73,22 +280,187 @@ func parseSamplingConfig() (config zap.SamplingConfig, err error) {
 	return
 }
 
-func parseOverrides() ([]outputcore.Override, error) {
-	raw := os.Getenv(EnvLogScopeLevel)
-	var overrides []outputcore.Override
+// parseOverrides parses scope overrides from the environment variable key, in the
+// format described in EnvLogScopeLevel.
+func parseOverrides(key string) ([]scopematch.Override, error) {
+	raw := os.Getenv(key)
+	var overrides []scopematch.Override
 	for _, kv := range strings.Split(raw, ",") {
 		if kv == "" {
 			continue
 		}
 
-		p := strings.SplitN(kv, "=", 2)
-		if len(p) != 2 {
-			return nil, fmt.Errorf("%s=%q is invalid", EnvLogScopeLevel, raw)
+		// Split on the last '=', since levels never contain '=' but patterns might
+		i := strings.LastIndex(kv, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%s=%q is invalid", key, raw)
+		}
+		pattern, err := scopematch.Parse(kv[:i])
+		if err != nil {
+			return nil, fmt.Errorf("%s=%q is invalid: %w", key, raw, err)
+		}
+		overrides = append(overrides, scopematch.Override{
+			Scope: pattern,
+			Level: Level(kv[i+1:]).Parse(),
+		})
+	}
+	return overrides, nil
+}
+
+// toOverrides converts scope levels keyed by scope patterns into scope overrides.
+func toOverrides(scopeLevels map[string]Level) ([]scopematch.Override, error) {
+	overrides := make([]scopematch.Override, 0, len(scopeLevels))
+	for scope, l := range scopeLevels {
+		pattern, err := scopematch.Parse(scope)
+		if err != nil {
+			return nil, err
 		}
-		overrides = append(overrides, outputcore.Override{
-			Scope: p[0],
-			Level: Level(p[1]).Parse(),
+		overrides = append(overrides, scopematch.Override{
+			Scope: pattern,
+			Level: l.Parse(),
 		})
 	}
+	// Equally specific overrides are resolved by order, so sort them for consistency.
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].Scope.String() < overrides[j].Scope.String()
+	})
 	return overrides, nil
 }
+
+// fromOverrides converts scope overrides into scope levels keyed by scope patterns.
+func fromOverrides(overrides []scopematch.Override) map[string]Level {
+	scopeLevels := make(map[string]Level, len(overrides))
+	for _, o := range overrides {
+		if _, ok := scopeLevels[o.Scope.String()]; !ok { // the first match is used
+			scopeLevels[o.Scope.String()] = levelFromZap(o.Level)
+		}
+	}
+	return scopeLevels
+}
+
+// parseSamplingRules parses sampling rules from EnvLogSamplingRules.
+func parseSamplingRules() ([]outputcore.SamplingRule, error) {
+	raw := os.Getenv(EnvLogSamplingRules)
+	var rules []OutputSamplingRule
+	for _, kv := range strings.Split(raw, ",") {
+		if kv == "" {
+			continue
+		}
+		rule, err := parseSamplingRule(kv)
+		if err != nil {
+			return nil, fmt.Errorf("%s=%q is invalid: %w", EnvLogSamplingRules, raw, err)
+		}
+		rules = append(rules, rule)
+	}
+	return toSamplingRules(rules)
+}
+
+// parseSamplingRule parses a rule in the format "SCOPE[:LEVEL]=INITIAL[/THEREAFTER]".
+func parseSamplingRule(kv string) (rule OutputSamplingRule, err error) {
+	// Split on the last '=', since the value never contains '=' but patterns might
+	i := strings.LastIndex(kv, "=")
+	if i < 0 {
+		return rule, fmt.Errorf("rule %q has no value", kv)
+	}
+	rule.Scope = kv[:i]
+	value := kv[i+1:]
+
+	// A level suffix cannot contain '/', unlike the end of a regular expression
+	if j := strings.LastIndex(rule.Scope, ":"); j >= 0 && !strings.Contains(rule.Scope[j:], "/") {
+		rule.Scope, rule.Level = rule.Scope[:j], Level(rule.Scope[j+1:])
+	}
+
+	initial, thereafter, _ := strings.Cut(value, "/")
+	if rule.Initial, err = strconv.Atoi(initial); err != nil {
+		return rule, fmt.Errorf("rule %q: %w", kv, err)
+	}
+	if thereafter != "" {
+		if rule.Thereafter, err = strconv.Atoi(thereafter); err != nil {
+			return rule, fmt.Errorf("rule %q: %w", kv, err)
+		}
+	}
+	return rule, nil
+}
+
+// toSamplingRules converts output sampling rules into sampling rules.
+func toSamplingRules(outputRules []OutputSamplingRule) ([]outputcore.SamplingRule, error) {
+	rules := make([]outputcore.SamplingRule, 0, len(outputRules))
+	for _, r := range outputRules {
+		pattern, err := scopematch.Parse(r.Scope)
+		if err != nil {
+			return nil, err
+		}
+		rule := outputcore.SamplingRule{
+			Scope: pattern,
+			SamplingConfig: zap.SamplingConfig{
+				Initial:    r.Initial,
+				Thereafter: r.Thereafter,
+			},
+		}
+		if r.Level != "" {
+			switch Level(strings.ToLower(string(r.Level))) {
+			case LevelDebug, LevelInfo, LevelWarn, LevelError:
+			default:
+				return nil, fmt.Errorf("sampling rule for scope %q has invalid level %q", r.Scope, r.Level)
+			}
+			level := r.Level.Parse()
+			rule.Level = &level
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// fromSamplingRules converts sampling rules into output sampling rules.
+func fromSamplingRules(rules []outputcore.SamplingRule) []OutputSamplingRule {
+	outputRules := make([]OutputSamplingRule, 0, len(rules))
+	for _, r := range rules {
+		rule := OutputSamplingRule{
+			Scope:      r.Scope.String(),
+			Initial:    r.Initial,
+			Thereafter: r.Thereafter,
+		}
+		if r.Level != nil {
+			rule.Level = levelFromZap(*r.Level)
+		}
+		outputRules = append(outputRules, rule)
+	}
+	return outputRules
+}
+
+// parseDedupOptions parses deduplication options from EnvLogDedup.
+func parseDedupOptions() (outputcore.DedupOptions, error) {
+	switch val := os.Getenv(EnvLogDedup); val {
+	case "", "off":
+		return outputcore.DedupOptions{}, nil
+	case "consecutive":
+		return outputcore.DedupOptions{Window: time.Minute, Consecutive: true}, nil
+	default:
+		window, err := time.ParseDuration(val)
+		if err != nil {
+			return outputcore.DedupOptions{}, fmt.Errorf("%s is invalid: %w", EnvLogDedup, err)
+		}
+		return outputcore.DedupOptions{Window: window}, nil
+	}
+}
+
+// parseFlightRecorderOptions parses flight recorder options from EnvLogFlightRecorder and
+// EnvLogFlightRecorderSize, and reports whether the flight recorder is enabled.
+func parseFlightRecorderOptions() (opts outputcore.FlightRecorderOptions, enabled bool, err error) {
+	switch level := Level(strings.ToLower(os.Getenv(EnvLogFlightRecorder))); level {
+	case "", LevelNone:
+		return opts, false, nil
+	case LevelDebug, LevelInfo, LevelWarn:
+		opts.Level = level.Parse()
+	default:
+		return opts, false, fmt.Errorf("%s=%q is invalid", EnvLogFlightRecorder, level)
+	}
+
+	if val, set := os.LookupEnv(EnvLogFlightRecorderSize); set {
+		opts.Size, err = strconv.Atoi(val)
+		if err != nil {
+			return opts, false, fmt.Errorf("%s is invalid: %w", EnvLogFlightRecorderSize, err)
+		}
+	}
+	return opts, true, nil
+}
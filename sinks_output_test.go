@@ -5,9 +5,14 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hexops/autogold/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/sinkcores/outputcore"
 )
 
 func TestOutputSink_Check(t *testing.T) {
@@ -116,6 +121,43 @@ foo.bar.baz error
 foo.bar.baz1 error
 `),
 	},
+		{
+			Name: "scope glob",
+			Env: map[string]string{
+				EnvLogLevel:      "error",
+				EnvLogScopeLevel: "*.bar=info,foo.*.baz1=debug",
+			},
+			// foo.bar.baz1 matches the more specific override
+			Want: autogold.Expect(`
+foo error
+foo.bar info
+foo.bar error
+foo.bar.baz info
+foo.bar.baz error
+foo.bar.baz1 debug
+foo.bar.baz1 info
+foo.bar.baz1 error
+`),
+		},
+		{
+			Name: "scope most specific",
+			Env: map[string]string{
+				EnvLogLevel:      "error",
+				EnvLogScopeLevel: "foo.bar.baz=debug,foo=info,/foo\\.bar/=warn",
+			},
+			// regular expressions are less specific than scope names, so foo.bar is at info
+			Want: autogold.Expect(`
+foo info
+foo error
+foo.bar info
+foo.bar error
+foo.bar.baz debug
+foo.bar.baz info
+foo.bar.baz error
+foo.bar.baz1 info
+foo.bar.baz1 error
+`),
+		},
 		{
 			Name: "scope restricting",
 			Env: map[string]string{
@@ -174,3 +216,203 @@ func unsetenv(t *testing.T, key string) {
 		os.Setenv(key, v)
 	})
 }
+
+func TestOutputSink_Update(t *testing.T) {
+	unsetenv(t, EnvLogScopeLevel)
+	t.Setenv(EnvLogLevel, "warn")
+
+	s := &outputSink{}
+	core, err := s.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := func(scope string, level Level) bool {
+		return core.Check(zapcore.Entry{LoggerName: scope, Level: level.Parse()}, nil) != nil
+	}
+
+	assert.False(t, enabled("foo", LevelInfo))
+	assert.True(t, enabled("foo", LevelWarn))
+
+	// Change root level
+	require.NoError(t, s.update(SinksConfig{Output: &OutputSink{Level: LevelInfo}}))
+	assert.True(t, enabled("foo", LevelInfo))
+	assert.False(t, enabled("foo", LevelDebug))
+
+	// Enable debug logging for one scope, the most specific override wins
+	require.NoError(t, s.update(SinksConfig{Output: &OutputSink{
+		ScopeLevels: map[string]Level{
+			"foo":     LevelDebug,
+			"foo.bar": LevelError,
+		},
+	}}))
+	assert.True(t, zapcore.LevelOf(core).Enabled(zapcore.DebugLevel))
+	assert.True(t, enabled("foo", LevelDebug))
+	assert.True(t, enabled("foo.baz", LevelDebug))
+	assert.False(t, enabled("foo.bar", LevelWarn))
+	assert.False(t, enabled("bar", LevelInfo))
+
+	// Unset output configuration leaves the current configuration unchanged
+	require.NoError(t, s.update(SinksConfig{}))
+	assert.True(t, enabled("foo", LevelDebug))
+	assert.False(t, enabled("foo.bar", LevelWarn))
+
+	// Revert to environment configuration
+	require.NoError(t, s.update(SinksConfig{Output: &OutputSink{}}))
+	assert.False(t, zapcore.LevelOf(core).Enabled(zapcore.DebugLevel))
+	assert.False(t, enabled("foo", LevelDebug))
+	assert.True(t, enabled("foo", LevelWarn))
+}
+
+func TestParseSamplingConfig(t *testing.T) {
+	t.Setenv(EnvLogSamplingInitial, "5")
+	t.Setenv(EnvLogSamplingThereafter, "50")
+
+	config, err := parseSamplingConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 5, config.Initial)
+	assert.Equal(t, 50, config.Thereafter)
+
+	// Each value defaults independently
+	unsetenv(t, EnvLogSamplingInitial)
+	config, err = parseSamplingConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 100, config.Initial)
+	assert.Equal(t, 50, config.Thereafter)
+
+	t.Setenv(EnvLogSamplingThereafter, "often")
+	_, err = parseSamplingConfig()
+	assert.Error(t, err)
+}
+
+func TestOutputSink_Sampling(t *testing.T) {
+	unsetenv(t, EnvLogSamplingThereafter)
+	t.Setenv(EnvLogLevel, "info")
+	t.Setenv(EnvLogSamplingInitial, "2")
+
+	s := &outputSink{}
+	core, err := s.build()
+	require.NoError(t, err)
+
+	countLogged := func() int {
+		var n int
+		for i := 0; i < 5; i++ {
+			if core.Check(zapcore.Entry{Level: zapcore.InfoLevel, Message: "sampled", Time: time.Now()}, nil) != nil {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 2, countLogged())
+	assert.Equal(t, &OutputSampling{Initial: 2, Thereafter: 100}, s.config().Sampling)
+
+	// Disable sampling
+	require.NoError(t, s.update(SinksConfig{Output: &OutputSink{Sampling: &OutputSampling{}}}))
+	assert.Equal(t, 5, countLogged())
+	assert.Equal(t, &OutputSampling{}, s.config().Sampling)
+}
+
+func TestOutputSink_SamplingRules(t *testing.T) {
+	unsetenv(t, EnvLogSamplingThereafter)
+	t.Setenv(EnvLogLevel, "debug")
+	t.Setenv(EnvLogSamplingInitial, "2")
+	t.Setenv(EnvLogSamplingRules, "**:error=0,search.zoekt:debug=1/1000,/chatty\\.(a|b)/=3")
+
+	s := &outputSink{}
+	core, err := s.build()
+	require.NoError(t, err)
+
+	countLogged := func(scope string, level zapcore.Level) int {
+		var n int
+		for i := 0; i < 5; i++ {
+			if core.Check(zapcore.Entry{LoggerName: scope, Level: level, Message: "sampled", Time: time.Now()}, nil) != nil {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 5, countLogged("search.zoekt", zapcore.ErrorLevel))
+	assert.Equal(t, 1, countLogged("search.zoekt", zapcore.DebugLevel))
+	assert.Equal(t, 2, countLogged("search.zoekt", zapcore.InfoLevel))
+	assert.Equal(t, 3, countLogged("chatty.a", zapcore.InfoLevel))
+	// Entries with the same message are counted separately per scope
+	assert.Equal(t, 2, countLogged("foo", zapcore.InfoLevel))
+	assert.Equal(t, 2, countLogged("bar", zapcore.InfoLevel))
+
+	assert.Equal(t, []OutputSamplingRule{
+		{Scope: "**", Level: LevelError, Initial: 0},
+		{Scope: "search.zoekt", Level: LevelDebug, Initial: 1, Thereafter: 1000},
+		{Scope: `/chatty\.(a|b)/`, Initial: 3},
+	}, s.config().SamplingRules)
+
+	// Replace rules at runtime
+	require.NoError(t, s.update(SinksConfig{Output: &OutputSink{
+		SamplingRules: []OutputSamplingRule{{Scope: "foo", Initial: 4}},
+	}}))
+	assert.Equal(t, 4, countLogged("foo.bar", zapcore.WarnLevel))
+	assert.Equal(t, 2, countLogged("search.zoekt", zapcore.WarnLevel))
+
+	// Remove all rules
+	require.NoError(t, s.update(SinksConfig{Output: &OutputSink{
+		SamplingRules: []OutputSamplingRule{},
+	}}))
+	assert.Equal(t, 2, countLogged("search.zoekt", zapcore.ErrorLevel))
+	assert.Empty(t, s.config().SamplingRules)
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, rules := range []string{
+			"foo",
+			"foo=bar",
+			"foo=1/bar",
+			"foo:verbose=1",
+			"foo..bar=1",
+		} {
+			t.Setenv(EnvLogSamplingRules, rules)
+			_, err := (&outputSink{}).build()
+			assert.Error(t, err, rules)
+		}
+	})
+}
+
+func TestParseDedupOptions(t *testing.T) {
+	for val, want := range map[string]outputcore.DedupOptions{
+		"":            {},
+		"off":         {},
+		"consecutive": {Window: time.Minute, Consecutive: true},
+		"10s":         {Window: 10 * time.Second},
+	} {
+		t.Setenv(EnvLogDedup, val)
+		got, err := parseDedupOptions()
+		require.NoError(t, err, val)
+		assert.Equal(t, want, got, val)
+	}
+
+	t.Setenv(EnvLogDedup, "forever")
+	_, err := (&outputSink{}).build()
+	assert.Error(t, err)
+}
+
+func TestParseFlightRecorderOptions(t *testing.T) {
+	unsetenv(t, EnvLogFlightRecorderSize)
+	t.Setenv(EnvLogFlightRecorder, "")
+	_, enabled, err := parseFlightRecorderOptions()
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	t.Setenv(EnvLogFlightRecorder, "debug")
+	opts, enabled, err := parseFlightRecorderOptions()
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	assert.Equal(t, outputcore.FlightRecorderOptions{Level: zapcore.DebugLevel}, opts)
+
+	t.Setenv(EnvLogFlightRecorderSize, "10")
+	opts, _, err = parseFlightRecorderOptions()
+	require.NoError(t, err)
+	assert.Equal(t, outputcore.FlightRecorderOptions{Level: zapcore.DebugLevel, Size: 10}, opts)
+
+	for _, invalid := range [][2]string{{"error", "10"}, {"debug", "ten"}} {
+		t.Setenv(EnvLogFlightRecorder, invalid[0])
+		t.Setenv(EnvLogFlightRecorderSize, invalid[1])
+		_, err := (&outputSink{}).build()
+		assert.Error(t, err, invalid)
+	}
+}
@@ -1,38 +1,170 @@
 package log
 
 import (
+	"fmt"
+	"io"
+	"time"
+
 	"github.com/getsentry/sentry-go"
 	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/sourcegraph/log/internal/scopematch"
 	"github.com/sourcegraph/log/internal/sinkcores/sentrycore"
 )
 
-// SentrySink reports all warning-level and above log messages that contain an error field
+// SentrySink reports all error-level and above log messages that contain an error field
 // (via the `log.Error(err)` or `log.NamedError(name, err)` field constructors) to Sentry,
 // complete with stacktrace data and any additional context logged in the corresponding
-// log message (including anything accumulated on a sub-logger).
+// log message (including anything accumulated on a sub-logger). The level at which
+// messages are reported can be changed with Level and ScopeLevels.
 type SentrySink struct {
 	// ClientOptions expose various options to configure the Sentry client
 	sentry.ClientOptions
+	// Level is the minimum level at which entries with errors are reported. Defaults to
+	// LevelError.
+	Level Level
+	// ScopeLevels overrides Level for specific scopes and their children. It is keyed by
+	// scope patterns as described in EnvLogScopeLevel - for example, LevelNone silences
+	// reports from a scope and LevelWarn reports warnings as well. If nil, the
+	// overrides configured with EnvLogSentryScopeLevel are used.
+	ScopeLevels map[string]Level
+	// CaptureMessages reports error-level and above log messages without an error field
+	// as message events, grouped by scope and message, so that errors logged without
+	// an underlying error are reported as well.
+	CaptureMessages bool
+	// Breadcrumbs configures the recording of log messages below the reported level as
+	// breadcrumbs, which are attached to later reports from the same trace, or from the
+	// same scope or its parent scopes. If nil, breadcrumbs are not recorded.
+	Breadcrumbs *SentryBreadcrumbs
+	// Grouping is the policy for grouping reports into Sentry issues, which applies to
+	// log messages without a SentryFingerprint field. Defaults to SentryGroupDefault.
+	Grouping SentryGrouping
+	// RateLimit configures a rate limit for similar reports, which are reports of log
+	// messages from the same scope with the same message and error cause, so that a
+	// noisy failure does not exhaust the Sentry quota or crowd out other reports.
+	// Suppressed reports are summarized periodically. If nil, reports are not rate
+	// limited.
+	RateLimit *SentryRateLimit
+	// StatsInterval is the interval at which the counters in SentryStats are logged on
+	// the "log.sentry" scope when they change, at warn level if reports were dropped or
+	// failed since the previous entry. If not positive, the counters are not logged.
+	// It cannot be changed with Update.
+	StatsInterval time.Duration
+}
+
+// SentryStats holds cumulative counts of reports handled by the Sentry sink, so that
+// failures of error reporting itself can be detected.
+type SentryStats struct {
+	// Queued is the number of reports queued to be sent.
+	Queued uint64
+	// Dropped is the number of reports dropped because the queue was full.
+	Dropped uint64
+	// RateLimited is the number of reports suppressed by SentrySink.RateLimit.
+	RateLimited uint64
+	// Sent is the number of events handed to the Sentry transport.
+	Sent uint64
+	// Discarded is the number of events discarded by the Sentry client, for example
+	// due to ClientOptions.SampleRate or BeforeSend.
+	Discarded uint64
+	// Failed is the number of events that the Sentry transport may not have delivered,
+	// because they were still pending 5 seconds after being sent or when a flush timed
+	// out. The Sentry client does not surface other delivery failures.
+	Failed uint64
+}
+
+// SentryRateLimit configures a token bucket rate limit for similar Sentry reports.
+type SentryRateLimit struct {
+	// Burst is the number of similar reports that are sent before they are limited.
+	// Defaults to 10.
+	Burst int
+	// Every is the interval at which another similar report is allowed once the burst
+	// is used up. Defaults to 1 minute.
+	Every time.Duration
+	// SummaryInterval is the interval at which summaries of suppressed reports are
+	// sent. Defaults to 1 minute.
+	SummaryInterval time.Duration
+}
+
+// SentryGrouping is a policy for grouping reports into Sentry issues, so that errors with
+// dynamic text are not split into many issues.
+type SentryGrouping string
+
+const (
+	// SentryGroupDefault leaves grouping to Sentry, which groups reports by their stack
+	// trace, or by their message if they have none.
+	SentryGroupDefault SentryGrouping = ""
+	// SentryGroupByScopeMessage groups reports by the scope and message of the log
+	// message.
+	SentryGroupByScopeMessage SentryGrouping = "scope-message"
+	// SentryGroupByErrorType groups reports by the scope of the log message and the
+	// type of the cause of the error.
+	SentryGroupByErrorType SentryGrouping = "error-type"
+	// SentryGroupByCause groups reports by the scope of the log message and the
+	// message of the cause of the error.
+	SentryGroupByCause SentryGrouping = "cause"
+)
+
+func (g SentryGrouping) parse() (sentrycore.Grouping, error) {
+	switch g {
+	case SentryGroupDefault:
+		return sentrycore.GroupDefault, nil
+	case SentryGroupByScopeMessage:
+		return sentrycore.GroupByScopeMessage, nil
+	case SentryGroupByErrorType:
+		return sentrycore.GroupByErrorType, nil
+	case SentryGroupByCause:
+		return sentrycore.GroupByCause, nil
+	}
+	return sentrycore.GroupDefault, fmt.Errorf("SentrySink: unknown Grouping %q", g)
+}
+
+// SentryBreadcrumbs configures the recording of Sentry breadcrumbs.
+type SentryBreadcrumbs struct {
+	// Level is the minimum level of log messages to record. Defaults to LevelInfo.
+	Level Level
+	// MaxCount is the maximum number of breadcrumbs to attach to a report. Defaults
+	// to 20.
+	MaxCount int
+	// MaxAge is the maximum age of breadcrumbs to attach to a report. Defaults to 5
+	// minutes.
+	MaxAge time.Duration
 }
 
 type sentrySink struct {
 	SentrySink
 
 	core *sentrycore.Core
+
+	// statsLogger is where counters are logged, which defaults to the "log.sentry"
+	// scope.
+	statsLogger Logger
+	// stopStats and statsDone are set if counters are logged.
+	stopStats chan struct{}
+	statsDone chan struct{}
 }
 
+var _ io.Closer = &sentrySink{}
+
 // NewSentrySink instantiates a Sentry sink to provide to `log.Init` with the following default values:
 // - SampleRate: 0.1
 // To provide different values see `NewSentrySinkWith`
 func NewSentrySink() Sink {
-	return &sentrySink{SentrySink: SentrySink{sentrycore.DefaultSentryClientOptions}}
+	return &sentrySink{SentrySink: SentrySink{ClientOptions: sentrycore.DefaultSentryClientOptions}}
 }
 
 // NewSentrySinkWith instantiates a Sentry sink to provide to `log.Init` with the values provided in SentrySink.
 func NewSentrySinkWith(s SentrySink) Sink {
-	return &sentrySink{SentrySink: SentrySink{s.ClientOptions}}
+	return &sentrySink{SentrySink: SentrySink{
+		ClientOptions:   s.ClientOptions,
+		Level:           s.Level,
+		ScopeLevels:     s.ScopeLevels,
+		CaptureMessages: s.CaptureMessages,
+		Breadcrumbs:     s.Breadcrumbs,
+		Grouping:        s.Grouping,
+		RateLimit:       s.RateLimit,
+		StatsInterval:   s.StatsInterval,
+	}}
 }
 
 func (s *sentrySink) Name() string { return "SentrySink" }
@@ -42,16 +174,94 @@ func (s *sentrySink) build() (zapcore.Core, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.core = sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+	core := sentrycore.NewCore(sentry.NewHub(client, sentry.NewScope()))
+	if err := configureSentryCore(core, s.SentrySink); err != nil {
+		return nil, err
+	}
+	s.core = core
+
+	if s.StatsInterval > 0 {
+		s.stopStats = make(chan struct{})
+		s.statsDone = make(chan struct{})
+		go s.logStats(s.StatsInterval)
+	}
 	return s.core, nil
 }
 
+// Close stops logging counters.
+func (s *sentrySink) Close() error {
+	if s.stopStats != nil {
+		close(s.stopStats)
+		<-s.statsDone
+		s.stopStats = nil
+	}
+	return nil
+}
+
+// stats returns the cumulative counts of reports handled by the sink.
+func (s *sentrySink) stats() SentryStats {
+	return SentryStats(s.core.Stats())
+}
+
+// logStats logs the counters every interval if they changed, until Close is called.
+func (s *sentrySink) logStats(interval time.Duration) {
+	defer close(s.statsDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last SentryStats
+	for {
+		select {
+		case <-s.stopStats:
+			return
+		case <-ticker.C:
+			current := s.stats()
+			if current == last {
+				continue
+			}
+			if s.statsLogger == nil {
+				// The global logger is only initialized after sinks are built
+				s.statsLogger = Scoped("log.sentry")
+			}
+			logSentryStats(s.statsLogger, last, current)
+			last = current
+		}
+	}
+}
+
+// logSentryStats logs current, at warn level if reports were dropped or failed since last.
+func logSentryStats(logger Logger, last, current SentryStats) {
+	fields := []Field{
+		Uint64("queued", current.Queued),
+		Uint64("dropped", current.Dropped),
+		Uint64("rateLimited", current.RateLimited),
+		Uint64("sent", current.Sent),
+		Uint64("discarded", current.Discarded),
+		Uint64("failed", current.Failed),
+	}
+	if current.Dropped > last.Dropped || current.Failed > last.Failed {
+		logger.Warn("Sentry reports were dropped or failed", fields...)
+	} else {
+		logger.Info("Sentry reports", fields...)
+	}
+}
+
 func (s *sentrySink) update(updated SinksConfig) error {
 	if updated.Sentry == nil {
 		// use zero-value, effectively disabling sentry next
 		updated.Sentry = &SentrySink{}
 	}
 
+	if err := configureSentryCore(s.core, *updated.Sentry); err != nil {
+		return err
+	}
+	s.Level = updated.Sentry.Level
+	s.ScopeLevels = updated.Sentry.ScopeLevels
+	s.CaptureMessages = updated.Sentry.CaptureMessages
+	s.Breadcrumbs = updated.Sentry.Breadcrumbs
+	s.Grouping = updated.Sentry.Grouping
+	s.RateLimit = updated.Sentry.RateLimit
+
 	if cmp.Equal(s.ClientOptions, updated.Sentry.ClientOptions) {
 		return nil
 	}
@@ -66,3 +276,63 @@ func (s *sentrySink) update(updated SinksConfig) error {
 	s.core.SetHub(sentry.NewHub(client, sentry.NewScope()))
 	return nil
 }
+
+// configureSentryCore applies the configuration in s that can be changed without
+// recreating the Sentry client to core.
+func configureSentryCore(core *sentrycore.Core, s SentrySink) error {
+	level := zapcore.ErrorLevel
+	if s.Level != "" {
+		level = s.Level.Parse()
+	}
+	overrides, err := sentryOverrides(s.ScopeLevels)
+	if err != nil {
+		return err
+	}
+	grouping, err := s.Grouping.parse()
+	if err != nil {
+		return err
+	}
+	core.SetLevels(level, overrides)
+	core.SetCaptureMessages(s.CaptureMessages)
+	core.SetGrouping(grouping)
+
+	var breadcrumbs sentrycore.BreadcrumbOptions
+	if s.Breadcrumbs != nil {
+		level := s.Breadcrumbs.Level
+		if level == "" {
+			level = LevelInfo
+		}
+		breadcrumbs = sentrycore.BreadcrumbOptions{
+			Level:    level.Parse(),
+			MaxCount: withDefault(s.Breadcrumbs.MaxCount, 20),
+			MaxAge:   withDefault(s.Breadcrumbs.MaxAge, 5*time.Minute),
+		}
+	}
+	if breadcrumbs != core.Breadcrumbs() {
+		// Changing options discards recorded breadcrumbs, so only do so if needed
+		core.SetBreadcrumbs(breadcrumbs)
+	}
+
+	var rateLimit sentrycore.RateLimitOptions
+	if s.RateLimit != nil {
+		rateLimit = sentrycore.RateLimitOptions{
+			Burst:           withDefault(s.RateLimit.Burst, 10),
+			Every:           withDefault(s.RateLimit.Every, time.Minute),
+			SummaryInterval: withDefault(s.RateLimit.SummaryInterval, sentrycore.DefaultSummaryInterval),
+		}
+	}
+	if rateLimit != core.RateLimit() {
+		// Changing options discards current rate limits, so only do so if needed
+		core.SetRateLimit(rateLimit)
+	}
+	return nil
+}
+
+// sentryOverrides returns the scope overrides for the given scope levels, falling back
+// to the environment if unset.
+func sentryOverrides(scopeLevels map[string]Level) ([]scopematch.Override, error) {
+	if scopeLevels == nil {
+		return parseOverrides(EnvLogSentryScopeLevel)
+	}
+	return toOverrides(scopeLevels)
+}
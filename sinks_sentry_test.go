@@ -1,10 +1,17 @@
 package log
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sourcegraph/log/internal/sinkcores/sentrycore"
 )
 
 func TestNewSentrySink(t *testing.T) {
@@ -36,7 +43,7 @@ func TestSentrySinkUpdate(t *testing.T) {
 		assert.Nil(t, err)
 
 		err = s.update(SinksConfig{
-			&SentrySink{
+			Sentry: &SentrySink{
 				ClientOptions: sentry.ClientOptions{
 					Dsn:        "",
 					SampleRate: 0.3333,
@@ -52,3 +59,143 @@ func TestSentrySinkUpdate(t *testing.T) {
 		assert.Equal(t, 0.3333, ss.SampleRate)
 	})
 }
+
+func TestSentrySinkScopeLevels(t *testing.T) {
+	t.Run("overrides are read from the environment", func(t *testing.T) {
+		t.Setenv(EnvLogSentryScopeLevel, "noisy=none,critical=warn")
+		s := NewSentrySink()
+		core, err := s.build()
+		require.NoError(t, err)
+
+		assert.Nil(t, core.Check(zapcore.Entry{LoggerName: "noisy", Level: zapcore.ErrorLevel}, nil))
+		assert.NotNil(t, core.Check(zapcore.Entry{LoggerName: "critical.child", Level: zapcore.WarnLevel}, nil))
+		assert.Nil(t, core.Check(zapcore.Entry{LoggerName: "other", Level: zapcore.WarnLevel}, nil))
+		assert.NotNil(t, core.Check(zapcore.Entry{LoggerName: "other", Level: zapcore.ErrorLevel}, nil))
+	})
+
+	t.Run("overrides are updated", func(t *testing.T) {
+		s := NewSentrySinkWith(SentrySink{ScopeLevels: map[string]Level{"noisy": LevelNone}})
+		core, err := s.build()
+		require.NoError(t, err)
+		assert.Nil(t, core.Check(zapcore.Entry{LoggerName: "noisy", Level: zapcore.ErrorLevel}, nil))
+
+		err = s.update(SinksConfig{Sentry: &SentrySink{ScopeLevels: map[string]Level{"*.critical": LevelWarn}}})
+		require.NoError(t, err)
+		assert.NotNil(t, core.Check(zapcore.Entry{LoggerName: "noisy", Level: zapcore.ErrorLevel}, nil))
+		assert.NotNil(t, core.Check(zapcore.Entry{LoggerName: "foo.critical", Level: zapcore.WarnLevel}, nil))
+	})
+
+	t.Run("invalid overrides", func(t *testing.T) {
+		s := NewSentrySinkWith(SentrySink{ScopeLevels: map[string]Level{"/(/": LevelWarn}})
+		_, err := s.build()
+		assert.Error(t, err)
+	})
+}
+
+func TestSentrySinkLevel(t *testing.T) {
+	unsetenv(t, EnvLogSentryScopeLevel)
+
+	s := NewSentrySinkWith(SentrySink{Level: LevelWarn, CaptureMessages: true})
+	core, err := s.build()
+	require.NoError(t, err)
+	assert.True(t, core.Enabled(zapcore.WarnLevel))
+	assert.False(t, core.Enabled(zapcore.InfoLevel))
+
+	// Level is restored to the default on update
+	require.NoError(t, s.update(SinksConfig{Sentry: &SentrySink{}}))
+	assert.False(t, core.Enabled(zapcore.WarnLevel))
+	assert.True(t, core.Enabled(zapcore.ErrorLevel))
+	assert.False(t, s.(*sentrySink).CaptureMessages)
+}
+
+func TestSentrySinkBreadcrumbs(t *testing.T) {
+	s := NewSentrySinkWith(SentrySink{Breadcrumbs: &SentryBreadcrumbs{}})
+	_, err := s.build()
+	require.NoError(t, err)
+
+	core := s.(*sentrySink).core
+	assert.Equal(t, sentrycore.BreadcrumbOptions{
+		Level:    zapcore.InfoLevel,
+		MaxCount: 20,
+		MaxAge:   5 * time.Minute,
+	}, core.Breadcrumbs())
+	assert.True(t, core.Enabled(zapcore.InfoLevel))
+
+	require.NoError(t, s.update(SinksConfig{Sentry: &SentrySink{}}))
+	assert.Equal(t, sentrycore.BreadcrumbOptions{}, core.Breadcrumbs())
+	assert.False(t, core.Enabled(zapcore.InfoLevel))
+}
+
+func TestSentrySinkGrouping(t *testing.T) {
+	unsetenv(t, EnvLogSentryScopeLevel)
+
+	_, err := NewSentrySinkWith(SentrySink{Grouping: "bogus"}).build()
+	assert.Error(t, err)
+
+	s := NewSentrySinkWith(SentrySink{Grouping: SentryGroupByCause})
+	core, err := s.build()
+	require.NoError(t, err)
+	sc := core.(*sentrycore.Core)
+	assert.Equal(t, sentrycore.GroupByCause, sc.Grouping())
+
+	require.NoError(t, s.update(SinksConfig{Sentry: &SentrySink{Grouping: SentryGroupByScopeMessage}}))
+	assert.Equal(t, sentrycore.GroupByScopeMessage, sc.Grouping())
+
+	assert.Error(t, s.update(SinksConfig{Sentry: &SentrySink{Grouping: "bogus"}}))
+	assert.Equal(t, SentryGroupByScopeMessage, s.(*sentrySink).Grouping)
+}
+
+func TestSentrySinkRateLimit(t *testing.T) {
+	unsetenv(t, EnvLogSentryScopeLevel)
+
+	s := NewSentrySinkWith(SentrySink{RateLimit: &SentryRateLimit{Burst: 5}})
+	core, err := s.build()
+	require.NoError(t, err)
+	sc := core.(*sentrycore.Core)
+	assert.Equal(t, sentrycore.RateLimitOptions{
+		Burst:           5,
+		Every:           time.Minute,
+		SummaryInterval: time.Minute,
+	}, sc.RateLimit())
+
+	require.NoError(t, s.update(SinksConfig{Sentry: &SentrySink{}}))
+	assert.Equal(t, sentrycore.RateLimitOptions{}, sc.RateLimit())
+}
+
+func TestSentrySinkStats(t *testing.T) {
+	unsetenv(t, EnvLogSentryScopeLevel)
+
+	observed, entries := observer.New(zapcore.DebugLevel)
+	s := NewSentrySinkWith(SentrySink{
+		ClientOptions: sentry.ClientOptions{Transport: &sentrycore.TransportMock{}},
+		StatsInterval: 10 * time.Millisecond,
+	}).(*sentrySink)
+	s.statsLogger = NoOp().(*zapAdapter).WithCore(func(zapcore.Core) zapcore.Core { return observed })
+	core, err := s.build()
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, core.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "failed"},
+		[]Field{Error(errors.New("boom"))}))
+	require.NoError(t, core.Sync())
+	assert.Equal(t, SentryStats{Queued: 1, Sent: 1}, s.stats())
+
+	require.Eventually(t, func() bool { return entries.Len() > 0 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, s.Close())
+	logged := entries.All()[0]
+	assert.Equal(t, "Sentry reports", logged.Message)
+	assert.Equal(t, zapcore.InfoLevel, logged.Level)
+	assert.Equal(t, uint64(1), logged.ContextMap()["sent"])
+}
+
+func TestLogSentryStats(t *testing.T) {
+	observed, entries := observer.New(zapcore.DebugLevel)
+	logger := NoOp().(*zapAdapter).WithCore(func(zapcore.Core) zapcore.Core { return observed })
+
+	logSentryStats(logger, SentryStats{Sent: 1}, SentryStats{Sent: 2, Failed: 1})
+	logSentryStats(logger, SentryStats{Sent: 2, Failed: 1}, SentryStats{Sent: 3, Failed: 1})
+
+	require.Equal(t, 2, entries.Len())
+	assert.Equal(t, zapcore.WarnLevel, entries.All()[0].Level)
+	assert.Equal(t, zapcore.InfoLevel, entries.All()[1].Level)
+}
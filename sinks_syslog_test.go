@@ -0,0 +1,167 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syslogServer accepts TCP connections and reads octet-counted syslog frames from them.
+type syslogServer struct {
+	lis      net.Listener
+	received chan string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newSyslogServer(t *testing.T, addr string) *syslogServer {
+	t.Helper()
+	lis, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	s := &syslogServer{lis: lis, received: make(chan string, 100)}
+	t.Cleanup(s.close)
+	go s.serve()
+	return s
+}
+
+// close stops the server, closing all accepted connections.
+func (s *syslogServer) close() {
+	_ = s.lis.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+}
+
+func (s *syslogServer) serve() {
+	for {
+		c, err := s.lis.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, c)
+		s.mu.Unlock()
+		go func() {
+			defer c.Close()
+			r := bufio.NewReader(c)
+			for {
+				length, err := r.ReadString(' ')
+				if err != nil {
+					return
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(length))
+				if err != nil {
+					return
+				}
+				frame := make([]byte, n)
+				if _, err := io.ReadFull(r, frame); err != nil {
+					return
+				}
+				s.received <- string(frame)
+			}
+		}()
+	}
+}
+
+// next returns the next message received, failing the test if none is received.
+func (s *syslogServer) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case msg := <-s.received:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return ""
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	t.Run("structured data ID is required", func(t *testing.T) {
+		_, err := sinks{NewSyslogSink(SyslogSink{Network: "udp", Address: "127.0.0.1:514"})}.build()
+		assert.ErrorContains(t, err, "StructuredDataID is required")
+
+		_, err = sinks{NewSyslogSink(SyslogSink{Network: "udp", Address: "127.0.0.1:514", StructuredDataID: "attributes"})}.build()
+		assert.Error(t, err)
+	})
+
+	t.Run("frames messages over TCP", func(t *testing.T) {
+		server := newSyslogServer(t, "127.0.0.1:0")
+		logger, callbacks := initSinks(t, "foo", NewSyslogSink(SyslogSink{
+			Network:  "tcp",
+			Address:  server.lis.Addr().String(),
+			Facility: SyslogFacilityLocal0,
+			Level:    LevelInfo,
+			// Uses the private enterprise number reserved for documentation
+			StructuredDataID: "attributes@32473",
+		}))
+
+		logger.Debug("dropped")
+		logger.Info("hello", String("key", "value"))
+		logger.Scoped("bar").Error("multi\nline")
+		callbacks.Sync()
+
+		// local0.info, with the Resource as HOSTNAME and APP-NAME, and the scope as MSGID
+		msg := server.next(t)
+		assert.True(t, strings.HasPrefix(msg, "<134>1 "), msg)
+		parts := strings.SplitN(msg, " ", 8)
+		assert.Equal(t, []string{"test-instance", "test-service"}, parts[2:4])
+		assert.Equal(t, "foo", parts[5])
+		assert.Equal(t, `[attributes@32473 key="value"] `+"\xEF\xBB\xBF"+"hello", strings.Join(parts[6:], " "))
+
+		// Frames may contain newlines
+		msg = server.next(t)
+		assert.True(t, strings.HasPrefix(msg, "<131>1 "), msg)
+		assert.True(t, strings.HasSuffix(msg, " foo.bar - \xEF\xBB\xBFmulti\nline"), msg)
+	})
+
+	t.Run("reconnects", func(t *testing.T) {
+		server := newSyslogServer(t, "127.0.0.1:0")
+		addr := server.lis.Addr().String()
+		logger, callbacks := initSinks(t, "foo", NewSyslogSink(SyslogSink{
+			Network:          "tcp",
+			Address:          addr,
+			StructuredDataID: "attributes@32473",
+		}))
+
+		logger.Warn("first")
+		callbacks.Sync()
+		assert.Contains(t, server.next(t), "first")
+
+		// Entries cannot be sent while the server is down
+		server.close()
+		require.Eventually(t, func() bool {
+			logger.Warn("lost")
+			callbacks.Sync()
+			stats, ok := callbacks.SyslogStats()
+			return ok && stats.Failed > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// Once the server is back, entries are sent again
+		server = newSyslogServer(t, addr)
+		require.Eventually(t, func() bool {
+			logger.Warn("second")
+			callbacks.Sync()
+			for {
+				select {
+				case msg := <-server.received:
+					if strings.HasSuffix(msg, "second") {
+						return true
+					}
+				default:
+					return false
+				}
+			}
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log/internal/encoders"
+	"github.com/sourcegraph/log/internal/otelfields"
+)
+
+// initSinks builds the given sinks and a root logger for them like Init does, but
+// without the output sink or the global logger. It returns a Logger with the given
+// scope, and the callbacks Init would return. The sinks are closed when the test ends.
+func initSinks(t *testing.T, scope string, s ...Sink) (Logger, *PostInitCallbacks) {
+	t.Helper()
+	ss := sinks(s)
+	cores, err := ss.build()
+	require.NoError(t, err)
+
+	root := zap.New(zapcore.NewTee(cores...)).
+		With(zap.Object(otelfields.ResourceFieldKey, &encoders.ResourceEncoder{
+			Resource: Resource{Name: "test-service", InstanceID: "test-instance"},
+		}))
+	closed := false
+	callbacks := &PostInitCallbacks{
+		Sync: func() { _ = root.Sync() },
+		Close: func() {
+			_ = root.Sync()
+			ss.close()
+			closed = true
+		},
+		Update: ss.update,
+		sinks:  ss,
+	}
+	t.Cleanup(func() {
+		if !closed {
+			ss.close()
+		}
+	})
+
+	logger := &zapAdapter{Logger: root, rootLogger: root}
+	return logger.Scoped(scope).With(otelfields.AttributesNamespace), callbacks
+}
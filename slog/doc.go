@@ -0,0 +1,2 @@
+// slog provides adapters for the standard library's "log/slog" package.
+package slog
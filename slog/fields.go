@@ -0,0 +1,150 @@
+package slog
+
+import (
+	"log/slog"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log"
+)
+
+// toLogFields converts slog attributes into log fields, following the slog.Handler
+// rules for attributes: empty attributes are ignored, and groups with empty keys are
+// inlined.
+func toLogFields(attrs []slog.Attr) []log.Field {
+	fields := make([]log.Field, 0, len(attrs))
+	for _, attr := range attrs {
+		fields = appendLogField(fields, attr)
+	}
+	return fields
+}
+
+func appendLogField(fields []log.Field, attr slog.Attr) []log.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		if len(group) == 0 {
+			return fields
+		}
+		if attr.Key == "" {
+			// Inline groups with empty keys
+			for _, a := range group {
+				fields = appendLogField(fields, a)
+			}
+			return fields
+		}
+		return append(fields, log.Object(attr.Key, toLogFields(group)...))
+	case slog.KindString:
+		return append(fields, log.String(attr.Key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(fields, log.Int64(attr.Key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, log.Uint64(attr.Key, attr.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, log.Float64(attr.Key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, log.Bool(attr.Key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, log.Duration(attr.Key, attr.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, log.Time(attr.Key, attr.Value.Time()))
+	}
+
+	// Use our own error field constructor so that errors are picked up by sinks that
+	// handle errors, such as the Sentry sink.
+	if err, ok := attr.Value.Any().(error); ok {
+		return append(fields, log.NamedError(attr.Key, err))
+	}
+	return append(fields, zap.Any(attr.Key, attr.Value.Any()))
+}
+
+// toSlogAttrs converts log fields into slog attributes. Fields following a namespace
+// field (see log.Namespace) are placed in a group named after the namespace.
+func toSlogAttrs(fields []log.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return append(attrs, slog.Group(f.Key, attrsToAny(toSlogAttrs(fields[i+1:]))...))
+		}
+		attrs = appendSlogAttrs(attrs, f)
+	}
+	return attrs
+}
+
+// withLogFields adds the given log fields to the handler. Unlike toSlogAttrs, fields
+// following a namespace field are placed in a group with slog.Handler.WithGroup, so that
+// attributes added to the handler later are also placed in the group.
+func withLogFields(h slog.Handler, fields []log.Field) slog.Handler {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			if len(attrs) > 0 {
+				h = h.WithAttrs(attrs)
+				attrs = nil
+			}
+			h = h.WithGroup(f.Key)
+			continue
+		}
+		attrs = appendSlogAttrs(attrs, f)
+	}
+	if len(attrs) > 0 {
+		h = h.WithAttrs(attrs)
+	}
+	return h
+}
+
+func appendSlogAttrs(attrs []slog.Attr, f log.Field) []slog.Attr {
+	switch f.Type {
+	case zapcore.SkipType:
+		return attrs
+	case zapcore.ErrorType:
+		// Retain the original error, so that handlers can treat it as such.
+		if err, ok := log.ErrorFromField(f); ok {
+			return append(attrs, slog.Any(f.Key, err))
+		}
+	}
+
+	// Let zap render everything else - this also handles inline fields, which may add
+	// multiple values.
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	for _, k := range sortedKeys(enc.Fields) {
+		attrs = append(attrs, toSlogAttr(k, enc.Fields[k]))
+	}
+	return attrs
+}
+
+func toSlogAttr(key string, v interface{}) slog.Attr {
+	if m, ok := v.(map[string]interface{}); ok {
+		group := make([]any, 0, len(m))
+		for _, k := range sortedKeys(m) {
+			group = append(group, toSlogAttr(k, m[k]))
+		}
+		return slog.Group(key, group...)
+	}
+	return slog.Any(key, v)
+}
+
+func attrsToAny(attrs []slog.Attr) []any {
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	return args
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log"
+)
+
+// Handler implements slog.Handler, backed by a sourcegraph/log.Logger.
+//
+// Groups opened with WithGroup are mapped to namespaces (see log.Namespace), and group
+// attributes are mapped to log.Object fields. Attributes holding an error are logged
+// with log.NamedError, so that they are reported by sinks that handle errors.
+type Handler struct {
+	logger log.Logger
+
+	// groups are groups opened with WithGroup that no attributes have been added to
+	// yet. As per the slog.Handler contract, groups without any attributes are omitted,
+	// so they are only added to the logger when attributes are added to them.
+	groups []string
+}
+
+var _ slog.Handler = &Handler{}
+
+// NewHandler instantiates a new slog.Handler that sends records to the given
+// log.Logger.
+func NewHandler(logger log.Logger) *Handler {
+	return &Handler{
+		// slog.Logger.Info -> slog.Logger.log -> Handler.Handle -> logger
+		logger: logger.AddCallerSkip(3),
+	}
+}
+
+// Enabled reports whether the underlying log.Logger handles records at the given level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return log.Enabled(ctx, h.logger, toLogLevel(level))
+}
+
+// Handle logs the given record with the underlying log.Logger, including any fields
+// and trace context carried by ctx.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]log.Field, 0, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = appendLogField(fields, attr)
+		return true
+	})
+	if len(fields) > 0 {
+		fields = append(h.namespaces(), fields...)
+	}
+
+	switch toZapLevel(r.Level) {
+	case zapcore.ErrorLevel:
+		log.ErrorCtx(ctx, h.logger, r.Message, fields...)
+	case zapcore.WarnLevel:
+		log.WarnCtx(ctx, h.logger, r.Message, fields...)
+	case zapcore.InfoLevel:
+		log.InfoCtx(ctx, h.logger, r.Message, fields...)
+	default:
+		log.DebugCtx(ctx, h.logger, r.Message, fields...)
+	}
+	return nil
+}
+
+// WithAttrs returns a new Handler whose logger has the given attributes as fields.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := toLogFields(attrs)
+	if len(fields) == 0 {
+		return h
+	}
+	return &Handler{
+		logger: h.logger.With(append(h.namespaces(), fields...)...),
+	}
+}
+
+// WithGroup returns a new Handler that adds all subsequent attributes to the named
+// group.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := make([]string, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+	return &Handler{
+		logger: h.logger,
+		groups: append(groups, name),
+	}
+}
+
+// namespaces returns fields that open all pending groups.
+func (h *Handler) namespaces() []log.Field {
+	fields := make([]log.Field, len(h.groups))
+	for i, g := range h.groups {
+		fields[i] = log.Namespace(g)
+	}
+	return fields
+}
@@ -0,0 +1,88 @@
+package slog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/log"
+	"github.com/sourcegraph/log/logtest"
+)
+
+func TestHandler(t *testing.T) {
+	logger, exportLogs := logtest.Captured(t)
+	l := slog.New(NewHandler(logger))
+
+	l.Debug("debug message", "key", "value")                                   // 0
+	l.With("some", "field").Info("info message", slog.Int("int", 1))           // 1
+	l.WithGroup("empty").Warn("warn message")                                  // 2
+	l.WithGroup("g").With("a", 1).WithGroup("h").Error("error", "b", 2)        // 3
+	l.Info("group attr", slog.Group("obj", "x", true), slog.Group("", "y", 1)) // 4
+	l.Error("error attr", "err", errors.New("oh no"))                          // 5
+	l.Log(context.Background(), slog.LevelError+4, "above error")              // 6
+	l.Info("durations", "d", time.Second, slog.Attr{})                         // 7
+
+	logs := exportLogs()
+	assert.Len(t, logs, 8)
+	for _, l := range logs {
+		assert.Equal(t, "TestHandler", l.Scope)
+	}
+
+	assert.Equal(t, log.LevelDebug, logs[0].Level)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, logs[0].Fields)
+
+	assert.Equal(t, log.LevelInfo, logs[1].Level)
+	assert.Equal(t, map[string]interface{}{"some": "field", "int": int64(1)}, logs[1].Fields)
+
+	// Groups without attributes are omitted
+	assert.Equal(t, log.LevelWarn, logs[2].Level)
+	assert.Empty(t, logs[2].Fields)
+
+	assert.Equal(t, log.LevelError, logs[3].Level)
+	assert.Equal(t, map[string]interface{}{
+		"g": map[string]interface{}{
+			"a": int64(1),
+			"h": map[string]interface{}{"b": int64(2)},
+		},
+	}, logs[3].Fields)
+
+	assert.Equal(t, map[string]interface{}{
+		"obj": map[string]interface{}{"x": true},
+		"y":   int64(1),
+	}, logs[4].Fields)
+
+	assert.Equal(t, map[string]interface{}{"err": "oh no"}, logs[5].Fields)
+
+	assert.Equal(t, log.LevelError, logs[6].Level)
+
+	assert.Equal(t, map[string]interface{}{"d": time.Second}, logs[7].Fields)
+}
+
+func TestHandlerContext(t *testing.T) {
+	logger, exportLogs := logtest.Captured(t)
+	l := slog.New(NewHandler(logger))
+
+	ctx := log.WithFields(context.Background(), log.String("request", "foo"))
+	ctx = log.WithTraceContext(ctx, log.TraceContext{TraceID: "1234abcde"})
+	l.InfoContext(ctx, "with context")
+
+	logs := exportLogs()
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "1234abcde", logs[0].Fields["TraceId"])
+	assert.Equal(t, "foo", logs[0].Fields["request"])
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	logger := logtest.ScopedWith(t, logtest.LoggerOptions{Level: log.LevelWarn})
+	h := NewHandler(logger)
+
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelError))
+
+	assert.False(t, NewHandler(log.NoOp()).Enabled(context.Background(), slog.LevelError))
+}
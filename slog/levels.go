@@ -0,0 +1,53 @@
+package slog
+
+import (
+	"log/slog"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log"
+)
+
+// toZapLevel maps slog levels, which may be arbitrary integers, onto the closest level
+// at or below it that is supported by log.Logger.
+func toZapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+// toLogLevel maps slog levels onto the closest log.Level at or below it, like
+// toZapLevel.
+func toLogLevel(level slog.Level) log.Level {
+	switch {
+	case level >= slog.LevelError:
+		return log.LevelError
+	case level >= slog.LevelWarn:
+		return log.LevelWarn
+	case level >= slog.LevelInfo:
+		return log.LevelInfo
+	default:
+		return log.LevelDebug
+	}
+}
+
+// toSlogLevel maps log.Logger levels onto slog levels.
+func toSlogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level >= zapcore.ErrorLevel:
+		return slog.LevelError
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
@@ -0,0 +1,215 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sourcegraph/log"
+)
+
+// scopeKey is the attribute key used to record the instrumentation scope of a
+// log.Logger created with FromSlog, matching the key used by log.Logger output.
+const scopeKey = "InstrumentationScope"
+
+// New instantiates a new slog.Logger that sends records to the given log.Logger. The
+// log.Logger can be retrieved again, including any attributes added with
+// slog.Logger.With and slog.Logger.WithGroup, with FromSlog.
+func New(logger log.Logger) *slog.Logger { return slog.New(NewHandler(logger)) }
+
+// FromSlog returns a log.Logger that sends entries to the given slog.Logger.
+//
+// If the slog.Logger was created with New or NewHandler, the underlying log.Logger is
+// returned directly with any attributes added to the slog.Logger, retaining its scope,
+// attributes, and trace context. Groups opened with slog.Logger.WithGroup are kept as
+// namespaces (see log.Namespace), so that fields added later are placed in them.
+// Otherwise, the scope of the returned log.Logger is
+// recorded in each record under the "InstrumentationScope" attribute, and trace context
+// is recorded under the "TraceId", "SpanId" and "TraceFlags" attributes.
+func FromSlog(l *slog.Logger) log.Logger {
+	if h, ok := l.Handler().(*Handler); ok {
+		// Undo the caller skip added in NewHandler
+		logger := h.logger.AddCallerSkip(-3)
+		if len(h.groups) > 0 {
+			logger = logger.With(h.namespaces()...)
+		}
+		return logger
+	}
+	return newSlogLogger(l.Handler())
+}
+
+// slogLogger implements log.Logger, backed by a slog.Handler.
+type slogLogger struct {
+	// root is the handler without any scope, trace, or attributes. It is used to
+	// rebuild handler when the scope or trace change.
+	root slog.Handler
+	// handler is root with scope, trace and attributes applied.
+	handler slog.Handler
+
+	// fullScope tracks the full name of the logger's scope.
+	fullScope string
+	// trace is the trace context of this logger, if any.
+	trace log.TraceContext
+	// attributes is a read-only copy of all attributes used in this logger, for the
+	// purposes of being able to rebuild handler from root.
+	attributes []log.Field
+
+	callerSkip int
+	minLevel   zapcore.Level
+}
+
+var _ log.ContextLogger = &slogLogger{}
+var _ log.EnabledLogger = &slogLogger{}
+
+func newSlogLogger(root slog.Handler) *slogLogger {
+	return &slogLogger{
+		root:     root,
+		handler:  root,
+		minLevel: zapcore.DebugLevel,
+	}
+}
+
+// rebuild returns a copy of l with handler rebuilt from root with the given scope and
+// trace.
+func (l *slogLogger) rebuild(fullScope string, trace log.TraceContext) *slogLogger {
+	var top []slog.Attr
+	if fullScope != "" {
+		top = append(top, slog.String(scopeKey, fullScope))
+	}
+	if trace.TraceID != "" {
+		top = append(top, slog.String("TraceId", trace.TraceID))
+	}
+	if trace.SpanID != "" {
+		top = append(top, slog.String("SpanId", trace.SpanID))
+	}
+	if trace.TraceFlags != 0 {
+		top = append(top, slog.String("TraceFlags", trace.TraceFlags.String()))
+	}
+
+	handler := l.root
+	if len(top) > 0 {
+		handler = handler.WithAttrs(top)
+	}
+
+	c := *l
+	c.handler = withLogFields(handler, l.attributes)
+	c.fullScope = fullScope
+	c.trace = trace
+	return &c
+}
+
+func (l *slogLogger) Scoped(scope string) log.Logger {
+	if l.fullScope != "" {
+		scope = l.fullScope + "." + scope
+	}
+	return l.rebuild(scope, l.trace)
+}
+
+func (l *slogLogger) With(fields ...log.Field) log.Logger {
+	c := *l
+	c.handler = withLogFields(l.handler, fields)
+	c.attributes = append(l.attributes[:len(l.attributes):len(l.attributes)], fields...)
+	return &c
+}
+
+func (l *slogLogger) WithTrace(trace log.TraceContext) log.Logger {
+	if trace.TraceID == "" && trace.SpanID == "" {
+		return l // no-op
+	}
+	return l.rebuild(l.fullScope, trace)
+}
+
+func (l *slogLogger) Debug(msg string, fields ...log.Field) {
+	l.log(context.Background(), zapcore.DebugLevel, msg, fields)
+}
+
+func (l *slogLogger) Info(msg string, fields ...log.Field) {
+	l.log(context.Background(), zapcore.InfoLevel, msg, fields)
+}
+
+func (l *slogLogger) Warn(msg string, fields ...log.Field) {
+	l.log(context.Background(), zapcore.WarnLevel, msg, fields)
+}
+
+func (l *slogLogger) Error(msg string, fields ...log.Field) {
+	l.log(context.Background(), zapcore.ErrorLevel, msg, fields)
+}
+
+// Fatal logs at error level, since slog has no fatal level, and then calls os.Exit(1).
+func (l *slogLogger) Fatal(msg string, fields ...log.Field) {
+	l.log(context.Background(), zapcore.ErrorLevel, msg, fields)
+	os.Exit(1)
+}
+
+func (l *slogLogger) DebugCtx(ctx context.Context, msg string, fields ...log.Field) {
+	l.log(ctx, zapcore.DebugLevel, msg, fields)
+}
+
+func (l *slogLogger) InfoCtx(ctx context.Context, msg string, fields ...log.Field) {
+	l.log(ctx, zapcore.InfoLevel, msg, fields)
+}
+
+func (l *slogLogger) WarnCtx(ctx context.Context, msg string, fields ...log.Field) {
+	l.log(ctx, zapcore.WarnLevel, msg, fields)
+}
+
+func (l *slogLogger) ErrorCtx(ctx context.Context, msg string, fields ...log.Field) {
+	l.log(ctx, zapcore.ErrorLevel, msg, fields)
+}
+
+func (l *slogLogger) AddCallerSkip(skip int) log.Logger {
+	c := *l
+	c.callerSkip += skip
+	return &c
+}
+
+func (l *slogLogger) IncreaseLevel(scope string, description string, level log.Level) log.Logger {
+	l.AddCallerSkip(1).Debug("logger.IncreaseLevel",
+		log.Object("scope",
+			log.String("scope", l.fullScope+"."+scope),
+			log.String("description", description)),
+		log.String("level", string(level)))
+
+	c := *l
+	if lvl := level.Parse(); lvl > c.minLevel {
+		c.minLevel = lvl
+	}
+	return &c
+}
+
+func (l *slogLogger) Enabled(ctx context.Context, level log.Level) bool {
+	return l.enabled(ctx, level.Parse())
+}
+
+func (l *slogLogger) enabled(ctx context.Context, level zapcore.Level) bool {
+	return level >= l.minLevel && l.handler.Enabled(ctx, toSlogLevel(level))
+}
+
+// log must only be called directly by the log.Logger methods of slogLogger, so that
+// the caller is correctly attributed.
+func (l *slogLogger) log(ctx context.Context, level zapcore.Level, msg string, fields []log.Field) {
+	if !l.enabled(ctx, level) {
+		return
+	}
+
+	// Apply fields and trace context carried by ctx
+	if ctxFields := log.FieldsFromContext(ctx); len(ctxFields) > 0 {
+		fields = append(ctxFields[:len(ctxFields):len(ctxFields)], fields...)
+	}
+	handler := l.handler
+	if trace, ok := log.TraceContextFromContext(ctx); ok {
+		handler = l.rebuild(l.fullScope, trace).handler
+	}
+
+	// runtime.Callers -> slogLogger.log -> slogLogger.Info -> caller
+	var pcs [1]uintptr
+	runtime.Callers(3+l.callerSkip, pcs[:])
+
+	r := slog.NewRecord(time.Now(), toSlogLevel(level), msg, pcs[0])
+	r.AddAttrs(toSlogAttrs(fields)...)
+	_ = handler.Handle(ctx, r)
+}
@@ -0,0 +1,132 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sourcegraph/log"
+	"github.com/sourcegraph/log/logtest"
+)
+
+func TestRoundTripFromLogger(t *testing.T) {
+	logger, exportLogs := logtest.Captured(t)
+
+	logger = logger.Scoped("foo").WithTrace(log.TraceContext{TraceID: "1234abcde"})
+	l := New(logger).With("some", "field")
+	FromSlog(l).Scoped("bar").Info("hello world")
+
+	logs := exportLogs()
+	require.Len(t, logs, 1)
+	assert.Equal(t, "TestRoundTripFromLogger.foo.bar", logs[0].Scope)
+	assert.Equal(t, "1234abcde", logs[0].Fields["TraceId"])
+	assert.Equal(t, "field", logs[0].Fields["some"])
+}
+
+func TestRoundTripFromLoggerGroups(t *testing.T) {
+	logger, exportLogs := logtest.Captured(t)
+
+	l := New(logger).With("top", "level").WithGroup("g").With("a", 1).WithGroup("h")
+	FromSlog(l).With(log.String("b", "2")).Info("hello world", log.Int("c", 3))
+
+	logs := exportLogs()
+	require.Len(t, logs, 1)
+	assert.Equal(t, "level", logs[0].Fields["top"])
+	assert.Equal(t, map[string]interface{}{
+		"a": int64(1),
+		"h": map[string]interface{}{
+			"b": "2",
+			"c": int64(3),
+		},
+	}, logs[0].Fields["g"])
+}
+
+func newJSONLogger(t *testing.T) (*slog.Logger, func() []map[string]any) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelDebug,
+	}))
+	return l, func() []map[string]any {
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var r map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &r))
+			delete(r, "time")
+			records = append(records, r)
+		}
+		buf.Reset()
+		return records
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	l, export := newJSONLogger(t)
+
+	logger := FromSlog(l).
+		Scoped("foo").
+		With(log.String("some", "field")).
+		WithTrace(log.TraceContext{TraceID: "1234abcde", SpanID: "1234"}).
+		Scoped("bar")
+
+	logger.Info("hello", log.Int("n", 1))                                           // 0
+	logger.With(log.Namespace("ns"), log.String("a", "b")).Warn("namespace")        // 1
+	logger.Error("error", log.Error(errors.New("oh no")))                           // 2
+	logger.Info("object", log.Object("obj", log.String("x", "y"), log.Int("z", 1))) // 3
+	logger.IncreaseLevel("quiet", "", log.LevelWarn).Info("dropped")                // 4
+
+	records := export()
+	require.Len(t, records, 5)
+
+	source := records[0]["source"].(map[string]any)
+	assert.True(t, strings.HasSuffix(source["file"].(string), "logger_test.go"))
+	delete(records[0], "source")
+	assert.Equal(t, map[string]any{
+		"level":                "INFO",
+		"msg":                  "hello",
+		"InstrumentationScope": "foo.bar",
+		"TraceId":              "1234abcde",
+		"SpanId":               "1234",
+		"some":                 "field",
+		"n":                    float64(1),
+	}, records[0])
+
+	assert.Equal(t, map[string]any{"a": "b"}, records[1]["ns"])
+	assert.Equal(t, "oh no", records[2]["error"])
+	assert.Equal(t, map[string]any{"x": "y", "z": float64(1)}, records[3]["obj"])
+	// Only the debug entry for IncreaseLevel is logged
+	assert.Equal(t, "logger.IncreaseLevel", records[4]["msg"])
+
+	t.Run("context", func(t *testing.T) {
+		ctx := log.WithFields(context.Background(), log.String("request", "foo"))
+		ctx = log.WithTraceContext(ctx, log.TraceContext{TraceID: "5678"})
+		log.InfoCtx(ctx, logger, "with context")
+
+		records := export()
+		require.Len(t, records, 1)
+		assert.Equal(t, "5678", records[0]["TraceId"])
+		assert.Equal(t, "foo", records[0]["request"])
+		assert.Equal(t, "foo.bar", records[0]["InstrumentationScope"])
+	})
+}
+
+func TestRoundTripFromSlog(t *testing.T) {
+	l, export := newJSONLogger(t)
+
+	New(FromSlog(l).Scoped("foo")).WithGroup("g").Info("hello", "a", 1)
+
+	records := export()
+	require.Len(t, records, 1)
+
+	source := records[0]["source"].(map[string]any)
+	assert.True(t, strings.HasSuffix(source["file"].(string), "logger_test.go"))
+	assert.Equal(t, "foo", records[0]["InstrumentationScope"])
+	assert.Equal(t, map[string]any{"a": float64(1)}, records[0]["g"])
+}
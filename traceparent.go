@@ -0,0 +1,199 @@
+package log
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// TraceparentHeader is the W3C Trace Context header that carries the trace ID, span
+	// ID, and trace flags of the caller.
+	//
+	// https://www.w3.org/TR/trace-context/#traceparent-header
+	TraceparentHeader = "traceparent"
+	// TracestateHeader is the W3C Trace Context header that carries vendor-specific
+	// trace identification data.
+	//
+	// https://www.w3.org/TR/trace-context/#tracestate-header
+	TracestateHeader = "tracestate"
+)
+
+const (
+	traceIDLength = 32
+	spanIDLength  = 16
+
+	// maxTracestateMembers is the maximum number of list-members in a tracestate.
+	maxTracestateMembers = 32
+)
+
+// ParseTraceparent parses the value of a W3C traceparent header into a TraceContext. An
+// error is returned if the header is malformed, or if the trace or span IDs are
+// invalid, e.g. all zeroes.
+//
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func ParseTraceparent(traceparent string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, fmt.Errorf("traceparent %q is malformed", traceparent)
+	}
+
+	version := parts[0]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" {
+		return TraceContext{}, fmt.Errorf("traceparent %q has invalid version", traceparent)
+	}
+	// Version 00 has exactly four parts, but future versions may append additional
+	// parts that we should ignore.
+	if version == "00" && len(parts) != 4 {
+		return TraceContext{}, fmt.Errorf("traceparent %q is malformed", traceparent)
+	}
+
+	flags := parts[3]
+	if len(flags) != 2 || !isLowerHex(flags) {
+		return TraceContext{}, fmt.Errorf("traceparent %q has invalid trace flags", traceparent)
+	}
+	flagsValue, _ := hex.DecodeString(flags)
+
+	tc := TraceContext{
+		TraceID:    parts[1],
+		SpanID:     parts[2],
+		TraceFlags: TraceFlags(flagsValue[0]),
+	}
+	if err := ValidateTraceContext(tc); err != nil {
+		return TraceContext{}, fmt.Errorf("traceparent %q is invalid: %w", traceparent, err)
+	}
+	return tc, nil
+}
+
+// ParseTraceContextHeaders parses the values of the W3C traceparent and tracestate
+// headers into a TraceContext. tracestate is optional, and as per the specification,
+// an invalid tracestate is discarded rather than treated as an error.
+//
+// https://www.w3.org/TR/trace-context/#tracestate-header
+func ParseTraceContextHeaders(traceparent, tracestate string) (TraceContext, error) {
+	tc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return TraceContext{}, err
+	}
+	if ts, err := parseTracestate(tracestate); err == nil {
+		tc.TraceState = ts
+	}
+	return tc, nil
+}
+
+// FormatTraceparent renders the given TraceContext as the value of a W3C traceparent
+// header. An error is returned if the TraceContext is not valid - see
+// ValidateTraceContext.
+//
+// The TraceContext's TraceState, if any, can be used as the value of the W3C
+// tracestate header as-is.
+func FormatTraceparent(tc TraceContext) (string, error) {
+	if err := ValidateTraceContext(tc); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("00-%s-%s-%s", tc.TraceID, tc.SpanID, tc.TraceFlags), nil
+}
+
+// ValidateTraceContext checks that the trace and span IDs of the given TraceContext are
+// valid W3C identifiers: lowercase hex strings of the correct length that are not all
+// zeroes. This is useful for sanitizing trace contexts provided by untrusted clients
+// before providing them to Logger.WithTrace.
+func ValidateTraceContext(tc TraceContext) error {
+	if err := validateID("trace ID", tc.TraceID, traceIDLength); err != nil {
+		return err
+	}
+	return validateID("span ID", tc.SpanID, spanIDLength)
+}
+
+func validateID(name, id string, length int) error {
+	if len(id) != length || !isLowerHex(id) {
+		return fmt.Errorf("%s %q must be %d lowercase hex characters", name, id, length)
+	}
+	if strings.Trim(id, "0") == "" {
+		return fmt.Errorf("%s must not be all zeroes", name)
+	}
+	return nil
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTracestate validates and normalizes the value of a W3C tracestate header,
+// dropping empty list-members.
+func parseTracestate(tracestate string) (string, error) {
+	var members []string
+	seen := make(map[string]struct{})
+	for _, member := range strings.Split(tracestate, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+		kv := strings.SplitN(member, "=", 2)
+		if len(kv) != 2 || !isTracestateKey(kv[0]) || !isTracestateValue(kv[1]) {
+			return "", fmt.Errorf("tracestate member %q is invalid", member)
+		}
+		if _, exists := seen[kv[0]]; exists {
+			return "", fmt.Errorf("tracestate key %q is duplicated", kv[0])
+		}
+		seen[kv[0]] = struct{}{}
+		members = append(members, member)
+	}
+	if len(members) > maxTracestateMembers {
+		return "", fmt.Errorf("tracestate has more than %d members", maxTracestateMembers)
+	}
+	return strings.Join(members, ","), nil
+}
+
+// isTracestateKey checks for a simple-key or multi-tenant-key:
+//
+//	key = simple-key / multi-tenant-key
+//	simple-key = lcalpha 0*255( lcalpha / DIGIT / "_" / "-"/ "*" / "/" )
+//	multi-tenant-key = tenant-id "@" system-id
+//	tenant-id = ( lcalpha / DIGIT ) 0*240( lcalpha / DIGIT / "_" / "-"/ "*" / "/" )
+//	system-id = lcalpha 0*13( lcalpha / DIGIT / "_" / "-"/ "*" / "/" )
+func isTracestateKey(key string) bool {
+	isKeyChar := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '-' || c == '*' || c == '/'
+	}
+	isValid := func(s string, maxLen int, allowLeadingDigit bool) bool {
+		if len(s) == 0 || len(s) > maxLen {
+			return false
+		}
+		if !(s[0] >= 'a' && s[0] <= 'z' || allowLeadingDigit && s[0] >= '0' && s[0] <= '9') {
+			return false
+		}
+		for i := 1; i < len(s); i++ {
+			if !isKeyChar(s[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if tenant, system, ok := strings.Cut(key, "@"); ok {
+		return isValid(tenant, 241, true) && isValid(system, 14, false)
+	}
+	return isValid(key, 256, false)
+}
+
+// isTracestateValue checks that the value consists of 1 to 256 printable ASCII
+// characters other than ',' and '=', and does not end with a space.
+func isTracestateValue(value string) bool {
+	if len(value) == 0 || len(value) > 256 || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
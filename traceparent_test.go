@@ -0,0 +1,120 @@
+package log_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sourcegraph/log"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		traceparent string
+		want        log.TraceContext
+		wantErr     bool
+	}{{
+		name:        "valid",
+		traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		want: log.TraceContext{
+			TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:     "00f067aa0ba902b7",
+			TraceFlags: log.TraceFlagsSampled,
+		},
+	}, {
+		name:        "future version with additional fields",
+		traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-foo",
+		want: log.TraceContext{
+			TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:  "00f067aa0ba902b7",
+		},
+	}, {
+		name:        "version 00 with additional fields",
+		traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo",
+		wantErr:     true,
+	}, {
+		name:        "invalid version",
+		traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		wantErr:     true,
+	}, {
+		name:        "all-zero trace ID",
+		traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		wantErr:     true,
+	}, {
+		name:        "all-zero span ID",
+		traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		wantErr:     true,
+	}, {
+		name:        "uppercase trace ID",
+		traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		wantErr:     true,
+	}, {
+		name:        "short span ID",
+		traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01",
+		wantErr:     true,
+	}, {
+		name:        "invalid flags",
+		traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-x1",
+		wantErr:     true,
+	}, {
+		name:        "garbage",
+		traceparent: "foobar",
+		wantErr:     true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := log.ParseTraceparent(tc.traceparent)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseTraceContextHeaders(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	t.Run("valid tracestate", func(t *testing.T) {
+		got, err := log.ParseTraceContextHeaders(traceparent, "congo=t61rcWkgMzE, ,tenant@vendor=foo")
+		require.NoError(t, err)
+		assert.Equal(t, "congo=t61rcWkgMzE,tenant@vendor=foo", got.TraceState)
+	})
+
+	t.Run("invalid tracestate is discarded", func(t *testing.T) {
+		got, err := log.ParseTraceContextHeaders(traceparent, "Congo=t61rcWkgMzE")
+		require.NoError(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID)
+		assert.Empty(t, got.TraceState)
+	})
+
+	t.Run("duplicate tracestate keys are discarded", func(t *testing.T) {
+		got, err := log.ParseTraceContextHeaders(traceparent, "congo=a,congo=b")
+		require.NoError(t, err)
+		assert.Empty(t, got.TraceState)
+	})
+
+	t.Run("invalid traceparent", func(t *testing.T) {
+		_, err := log.ParseTraceContextHeaders("", "congo=t61rcWkgMzE")
+		assert.Error(t, err)
+	})
+}
+
+func TestFormatTraceparent(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		tc, err := log.ParseTraceparent(traceparent)
+		require.NoError(t, err)
+		got, err := log.FormatTraceparent(tc)
+		require.NoError(t, err)
+		assert.Equal(t, traceparent, got)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := log.FormatTraceparent(log.TraceContext{TraceID: "1234abcde"})
+		assert.Error(t, err)
+	})
+}